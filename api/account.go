@@ -0,0 +1,38 @@
+package api
+
+import (
+	"github.com/leanovate/mite-go/domain"
+)
+
+// Account is the mite account the token belongs to
+type Account struct {
+	Id    domain.AccountId
+	Name  string
+	Title string
+}
+
+type accountResponse struct {
+	Account struct {
+		Id    int    `json:"id"`
+		Name  string `json:"name"`
+		Title string `json:"title"`
+	} `json:"account"`
+}
+
+// Account fetches the account of the token, e.g. to check the token is accepted
+func (c *Client) Account() (*Account, error) {
+	var res accountResponse
+
+	c.limiter.Wait()
+
+	err := c.rest.get("/account.json", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		Id:    domain.NewAccountId(res.Account.Id),
+		Name:  res.Account.Name,
+		Title: res.Account.Title,
+	}, nil
+}
@@ -1,130 +1,174 @@
 package api
 
 import (
-	"github.com/elliotchance/orderedmap"
+	"fmt"
 	"github.com/leanovate/mite-go/domain"
-	"github.com/leanovate/mite-go/mite"
 	log "github.com/sirupsen/logrus"
-	str2duration "github.com/xhit/go-str2duration/v2"
+	"mighty/reference"
+	"time"
 )
 
 type Client struct {
-	api mite.Api
+	rest    *rest
+	options Options
+	limiter *limiter
 }
 
-func New(baseUrl, tokenString string) (*Client, error) {
+// Options tune how the client talks to mite
+type Options struct {
+	// Concurrency is the number of calls sent to mite in parallel
+	Concurrency int
+	// RateLimit is the maximum number of calls per second, zero disables the limit
+	RateLimit float64
+	// Retries is the number of times a call failing with a transient error is repeated
+	Retries int
+	// RetryMaxWait caps the wait between two attempts of a call
+	RetryMaxWait time.Duration
+}
+
+func New(baseUrl, tokenString string, options Options) (*Client, error) {
+	limiter := newLimiter(options.RateLimit, options.Concurrency)
 
-	api, err := mite.NewApi(baseUrl, tokenString, "0.0.1")
+	rest, err := newRest(baseUrl, tokenString, newRetryTransport(options.Retries, options.RetryMaxWait, limiter))
 	if err != nil {
 		return nil, err
 	}
 
 	return &Client{
-		api,
+		rest,
+		options,
+		limiter,
 	}, nil
 }
 
-//FetchEntries returns the past entries from the current date to given duration in the past
-func (c *Client) FetchEntries(duration string) ([]*domain.TimeEntry, error) {
-	dur, err := str2duration.ParseDuration(duration)
+//FetchEntries returns the past entries of the given history, see HistoryRange
+func (c *Client) FetchEntries(history string) ([]*domain.TimeEntry, error) {
+	from, to, err := HistoryRange(history)
 	if err != nil {
 		return nil, err
 	}
 
-	to := domain.Today()
-	from := to.AddDuration(-dur)
-
-	log.Infof("Interpreting %s to fetching past entries from %s to %s ", duration, from.String(), to.String())
+	return c.FetchEntriesBetween(from, to)
+}
 
-	entries, err := c.api.TimeEntries(&domain.TimeEntryQuery{
-		UserId: domain.CurrentUser,
-		From:   &from,
-		To:     &to,
-		//Sort:      domain.SORT_BY_PROJECT,
-		//Direction: domain.SORT_DIRECTION_DESC,
-	})
+// FetchEntriesBetween returns the entries of the current user from the given dates, both inclusive
+func (c *Client) FetchEntriesBetween(from, to domain.LocalDate) ([]*domain.TimeEntry, error) {
+	c.limiter.Wait()
 
-	return entries, err
+	return c.timeEntries(from, to)
 }
 
-func (c *Client) SendEntriesToMite(entries []domain.TimeEntry) error {
-	log.Infof("Pushing %d entries to mite", len(entries))
-
-	for _, entry := range entries {
+// SendEntriesToMite applies the operations of the plan to mite using the configured concurrency. Every operation
+// is recorded in the journal before and after it is sent, operations the journal knows as done are skipped.
+// Failed operations don't stop the others, they are reported together as PushError.
+func (c *Client) SendEntriesToMite(plan *Plan, journal *Journal) error {
+	log.Infof("Pushing %d entries to mite", len(plan.Operations))
+
+	jobs := make([]job, 0, len(plan.Operations))
+	for _, op := range plan.Operations {
+		op := op
+		jobs = append(jobs, func() (string, error) {
+			return c.sendOperation(op, journal)
+		})
+	}
 
-		if entry.ProjectId < 1 || entry.ServiceId < 1 {
-			log.Fatalf("[%v] entry has no service id or project id, I'm ignoring it", entry)
-			return nil
-		}
+	pushErr := &PushError{Total: len(plan.Operations)}
 
-		if entry.Id == 0 {
-			timeEntry, err := c.api.CreateTimeEntry(&domain.TimeEntryCommand{
-				Date:      &entry.Date,
-				Minutes:   &entry.Minutes,
-				Note:      entry.Note,
-				UserId:    domain.CurrentUser,
-				ProjectId: entry.ProjectId,
-				ServiceId: entry.ServiceId,
-				Locked:    false,
-			})
-			if err != nil {
-				return err
-			}
-			log.Infof("Create %s| %s| %s| %s |%s ", entry.Date, entry.Minutes.String(), entry.ServiceName, entry.ProjectName, timeEntry.UpdatedAt)
-
-		} else {
-
-			if entry.Minutes.Value() == 0 {
-				log.Infof("Delete %s| %s| %s| %s |%s ", entry.Date, entry.Minutes.String(), entry.ServiceName, entry.ProjectName, entry.UpdatedAt)
-
-				err := c.api.DeleteTimeEntry(entry.Id)
-				if err != nil {
-					return err
-				}
-			} else {
-				log.Infof("Edit %s| %s| %s| %s |%s ", entry.Date, entry.Minutes.String(), entry.ServiceName, entry.ProjectName, entry.UpdatedAt)
-
-				err := c.api.EditTimeEntry(entry.Id, &domain.TimeEntryCommand{
-					Date:      &entry.Date,
-					Minutes:   &entry.Minutes,
-					Note:      entry.Note,
-					UserId:    domain.CurrentUser,
-					ProjectId: entry.ProjectId,
-					ServiceId: entry.ServiceId,
-					Locked:    false,
-				})
-				if err != nil {
-					return err
-				}
-			}
+	runJobs(c.options.Concurrency, jobs, func(index int, message string, err error) {
+		if err != nil {
+			failure := OperationError{plan.Operations[index], err}
+			log.Errorf("Failed to %s", failure)
+			pushErr.Failures = append(pushErr.Failures, failure)
 
+			return
 		}
 
+		log.Info(message)
+	})
+
+	if len(pushErr.Failures) > 0 {
+		return pushErr
 	}
+
 	return nil
 }
 
-func (c Client) FetchServiceProjects() (*orderedmap.OrderedMap, *orderedmap.OrderedMap, error) {
-	services, err := c.api.Services()
-	if err != nil {
-		return nil, nil, err
+func (c *Client) sendOperation(op Operation, journal *Journal) (string, error) {
+	entry := op.entry
+
+	if journal.Done(op) {
+		return fmt.Sprintf("Skipping %s %s| %s| %s| %s, it was already pushed", op.Action, entry.Date, entry.Minutes.String(),
+			entry.ServiceName, entry.ProjectName), nil
 	}
-	projects, err := c.api.Projects()
 
-	if err != nil {
-		return nil, nil, err
+	if err := journal.Begin(op); err != nil {
+		return "", err
 	}
 
-	serviceIdMap := orderedmap.NewOrderedMap()
+	c.limiter.Wait()
+
+	var createdId domain.TimeEntryId
 
-	for _, s := range services {
-		serviceIdMap.Set(s.Name, s.Id)
+	var message string
+
+	switch op.Action {
+	case ActionCreate:
+		id, updatedAt, err := c.createTimeEntry(&entry)
+		if err != nil {
+			return "", err
+		}
+		createdId = id
+		message = fmt.Sprintf("Create %s| %s| %s| %s |%s ", entry.Date, entry.Minutes.String(), entry.ServiceName, entry.ProjectName, updatedAt)
+	case ActionDelete:
+		err := c.deleteTimeEntry(entry.Id)
+		if err != nil {
+			return "", err
+		}
+		message = fmt.Sprintf("Delete %s| %s| %s| %s |%s ", entry.Date, entry.Minutes.String(), entry.ServiceName, entry.ProjectName, entry.UpdatedAt)
+	case ActionUpdate:
+		err := c.editTimeEntry(&entry)
+		if err != nil {
+			return "", err
+		}
+		message = fmt.Sprintf("Edit %s| %s| %s| %s |%s ", entry.Date, entry.Minutes.String(), entry.ServiceName, entry.ProjectName, entry.UpdatedAt)
 	}
-	projectIdMap := orderedmap.NewOrderedMap()
 
-	for _, p := range projects {
-		projectIdMap.Set(p.Name, p.Id)
+	return message, journal.Finish(op, createdId)
+}
+
+// FetchServiceProjects returns the services and projects of the account
+func (c Client) FetchServiceProjects() ([]reference.Service, []reference.Project, error) {
+	var services []reference.Service
+
+	var projects []reference.Project
+
+	var errs []error
+
+	runJobs(c.options.Concurrency, []job{
+		func() (string, error) {
+			var err error
+			services, err = c.fetchServices()
+
+			return fmt.Sprintf("Fetched %d services", len(services)), err
+		},
+		func() (string, error) {
+			var err error
+			projects, err = c.fetchProjects()
+
+			return fmt.Sprintf("Fetched %d projects", len(projects)), err
+		},
+	}, func(_ int, message string, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+
+		log.Debug(message)
+	})
+
+	if len(errs) > 0 {
+		return nil, nil, errs[0]
 	}
 
-	return serviceIdMap, projectIdMap, nil
+	return services, projects, nil
 }
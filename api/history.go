@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	log "github.com/sirupsen/logrus"
+	str2duration "github.com/xhit/go-str2duration/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyRangeSeparator separates the dates of an explicit history, e.g. 2026-01-01..2026-03-31
+const historyRangeSeparator = ".."
+
+// historyMonths matches whole months of history, e.g. "3 months"
+var historyMonths = regexp.MustCompile(`^(\d+)\s*months?$`)
+
+// HistoryRange returns the dates of the given history, both inclusive. The history is either
+//
+//   - a calendar period up to the current date: this-month, quarter or ytd
+//   - last-month, the whole previous month
+//   - a number of whole months up to the current date, e.g. "3 months" starts on the first of the month before last
+//   - explicit dates, e.g. 2026-01-01..2026-03-31
+//   - a duration in the past up to the current date, e.g. 4w
+func HistoryRange(history string) (domain.LocalDate, domain.LocalDate, error) {
+	from, to, err := parseHistory(strings.ToLower(strings.TrimSpace(history)))
+	if err != nil {
+		return domain.LocalDate{}, domain.LocalDate{}, err
+	}
+
+	log.Infof("Interpreting %s to fetching past entries from %s to %s ", history, from.String(), to.String())
+
+	return from, to, nil
+}
+
+func parseHistory(history string) (domain.LocalDate, domain.LocalDate, error) {
+	// today carries the time of day, the calendar periods start at midnight
+	today, err := domain.ParseLocalDate(domain.Today().String())
+	if err != nil {
+		return domain.LocalDate{}, domain.LocalDate{}, err
+	}
+
+	firstOfMonth := today.Add(0, 0, 1-today.Day())
+	month := int(time.Unix(today.Unix(), 0).Month())
+
+	switch history {
+	case "this-month":
+		return firstOfMonth, today, nil
+	case "last-month":
+		return firstOfMonth.Add(0, -1, 0), firstOfMonth.Add(0, 0, -1), nil
+	case "quarter":
+		return firstOfMonth.Add(0, -(month-1)%3, 0), today, nil
+	case "ytd":
+		return firstOfMonth.Add(0, 1-month, 0), today, nil
+	}
+
+	if m := historyMonths.FindStringSubmatch(history); m != nil {
+		months, err := strconv.Atoi(m[1])
+		if err != nil || months < 1 {
+			return domain.LocalDate{}, domain.LocalDate{}, fmt.Errorf("unable to parse history %q, expected at least 1 month", history)
+		}
+
+		return firstOfMonth.Add(0, 1-months, 0), today, nil
+	}
+
+	if strings.Contains(history, historyRangeSeparator) {
+		return parseHistoryDates(history)
+	}
+
+	dur, err := str2duration.ParseDuration(history)
+	if err != nil {
+		return domain.LocalDate{}, domain.LocalDate{}, fmt.Errorf("unable to parse history %q, expected e.g. 4w, "+
+			"this-month, last-month, \"3 months\", quarter, ytd or 2026-01-01..2026-03-31: %v", history, err)
+	}
+
+	to := domain.Today()
+
+	return to.AddDuration(-dur), to, nil
+}
+
+func parseHistoryDates(history string) (domain.LocalDate, domain.LocalDate, error) {
+	dates := strings.SplitN(history, historyRangeSeparator, 2)
+
+	from, err := domain.ParseLocalDate(strings.TrimSpace(dates[0]))
+	if err != nil {
+		return domain.LocalDate{}, domain.LocalDate{}, fmt.Errorf("unable to parse the start of history %q, expected yyyy-mm-dd", history)
+	}
+
+	to, err := domain.ParseLocalDate(strings.TrimSpace(dates[1]))
+	if err != nil {
+		return domain.LocalDate{}, domain.LocalDate{}, fmt.Errorf("unable to parse the end of history %q, expected yyyy-mm-dd", history)
+	}
+
+	if to.Before(from) {
+		return domain.LocalDate{}, domain.LocalDate{}, fmt.Errorf("history %q ends before it starts", history)
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"github.com/leanovate/mite-go/domain"
+	"testing"
+	"time"
+)
+
+func date(t *testing.T, s string) domain.LocalDate {
+	d, err := domain.ParseLocalDate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func TestParseHistory(t *testing.T) {
+	now, err := time.Parse("2006-01-02", domain.Today().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(year int, month time.Month, day int) string {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	}
+
+	today := now.Format("2006-01-02")
+	quarter := time.Month((int(now.Month())-1)/3*3 + 1)
+
+	tests := []struct {
+		history string
+		from    string
+		to      string
+	}{
+		{"this-month", day(now.Year(), now.Month(), 1), today},
+		{"last-month", day(now.Year(), now.Month()-1, 1), day(now.Year(), now.Month(), 0)},
+		{"quarter", day(now.Year(), quarter, 1), today},
+		{"ytd", day(now.Year(), time.January, 1), today},
+		{"1 month", day(now.Year(), now.Month(), 1), today},
+		{"3 months", day(now.Year(), now.Month()-2, 1), today},
+		{"2026-01-01..2026-03-31", "2026-01-01", "2026-03-31"},
+		{"2026-01-01 .. 2026-01-01", "2026-01-01", "2026-01-01"},
+		{"4w", now.AddDate(0, 0, -28).Format("2006-01-02"), today},
+	}
+
+	for _, test := range tests {
+		t.Run(test.history, func(t *testing.T) {
+			from, to, err := parseHistory(test.history)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if from.String() != date(t, test.from).String() || to.String() != date(t, test.to).String() {
+				t.Errorf("expected %s to %s, got %s to %s", test.from, test.to, from, to)
+			}
+		})
+	}
+}
+
+func TestParseHistoryRejectsInvalidHistories(t *testing.T) {
+	for _, history := range []string{"", "0 months", "soon", "2026-03-31..2026-01-01", "2026-13-01..2026-12-31", "2026-01-01.."} {
+		if from, to, err := parseHistory(history); err == nil {
+			t.Errorf("expected an error for %q, got %s to %s", history, from, to)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"time"
+)
+
+type JournalStatus string
+
+const (
+	JournalPending JournalStatus = "pending"
+	JournalDone    JournalStatus = "done"
+)
+
+// JournalRecord is a single line of the journal, an operation is first recorded as pending and then as done
+type JournalRecord struct {
+	Key       string             `json:"key"`
+	Action    Action             `json:"action"`
+	EntryId   domain.TimeEntryId `json:"entryId,omitempty"`
+	Date      string             `json:"date"`
+	Status    JournalStatus      `json:"status"`
+	CreatedId domain.TimeEntryId `json:"createdId,omitempty"`
+	Time      time.Time          `json:"time"`
+}
+
+// Journal is a write-ahead log of the operations of a push. It outlives an interrupted push,
+// so a resumed push skips the operations which already reached mite.
+type Journal struct {
+	mu      sync.Mutex
+	path    string
+	records map[string]JournalRecord
+	file    *os.File
+}
+
+// JournalPath returns the path of the journal belonging to the given workbook
+func JournalPath(excelFilePath string) string {
+	return excelFilePath + ".journal"
+}
+
+// JournalExists reports whether an earlier push of the workbook was interrupted
+func JournalExists(excelFilePath string) bool {
+	_, err := os.Stat(JournalPath(excelFilePath))
+	return err == nil
+}
+
+// OpenJournal opens the journal of the workbook, loading the records of an interrupted push if there is one
+func OpenJournal(excelFilePath string) (*Journal, error) {
+	j := &Journal{
+		path:    JournalPath(excelFilePath),
+		records: make(map[string]JournalRecord),
+	}
+
+	if f, err := os.Open(j.path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var record JournalRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				// a torn last line of a crashed push, the operation stays pending
+				log.Warnf("Ignoring unreadable journal line %q", scanner.Text())
+				continue
+			}
+
+			j.records[record.Key] = record
+		}
+
+		_ = f.Close()
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	j.file = f
+
+	return j, nil
+}
+
+// Reconcile settles the creates which were sent but never confirmed. Such a create is considered done when
+// one of the candidates, the entries which showed up in mite since the last pull, carries the same values.
+func (j *Journal) Reconcile(plan *Plan, candidates []*domain.TimeEntry) error {
+	claimed := make(map[domain.TimeEntryId]bool)
+
+	for _, record := range j.records {
+		if record.CreatedId != 0 {
+			claimed[record.CreatedId] = true
+		}
+	}
+
+	for _, op := range plan.Operations {
+		record, ok := j.records[op.key]
+		if !ok || op.Action != ActionCreate || record.Status != JournalPending {
+			continue
+		}
+
+		for _, candidate := range candidates {
+			if claimed[candidate.Id] || fingerprint(ActionCreate, 0, candidate) != fingerprint(ActionCreate, 0, &op.entry) {
+				continue
+			}
+
+			log.Infof("Found entry %s in mite for the unconfirmed create %s | %s | %s", candidate.Id, op.Date, op.Project, op.Service)
+
+			claimed[candidate.Id] = true
+
+			if err := j.Finish(op, candidate.Id); err != nil {
+				return err
+			}
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// Done reports whether the operation already reached mite in an earlier push
+func (j *Journal) Done(op Operation) bool {
+	if j == nil {
+		return false
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.records[op.key].Status == JournalDone
+}
+
+// Begin records the intent of sending the operation
+func (j *Journal) Begin(op Operation) error {
+	if j == nil {
+		return nil
+	}
+
+	return j.write(JournalRecord{
+		Key:     op.key,
+		Action:  op.Action,
+		EntryId: op.EntryId,
+		Date:    op.Date,
+		Status:  JournalPending,
+		Time:    time.Now(),
+	})
+}
+
+// Finish records that the operation reached mite, createdId is the id of a newly created entry
+func (j *Journal) Finish(op Operation, createdId domain.TimeEntryId) error {
+	if j == nil {
+		return nil
+	}
+
+	return j.write(JournalRecord{
+		Key:       op.key,
+		Action:    op.Action,
+		EntryId:   op.EntryId,
+		Date:      op.Date,
+		Status:    JournalDone,
+		CreatedId: createdId,
+		Time:      time.Now(),
+	})
+}
+
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+// RemoveJournal deletes the journal of the workbook, to be called once the pushed entries are pulled back into it
+func RemoveJournal(excelFilePath string) error {
+	err := os.Remove(JournalPath(excelFilePath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (j *Journal) write(record JournalRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err = j.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	j.records[record.Key] = record
+
+	return j.file.Sync()
+}
+
+// fingerprint identifies an operation by its values, so it can be recognised across pushes
+func fingerprint(action Action, id domain.TimeEntryId, entry *domain.TimeEntry) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%s|%d|%s|%s|%s", action, id, entry.Date, entry.Minutes.Value(),
+		entry.ProjectId, entry.ServiceId, entry.Note)))
+
+	return fmt.Sprintf("%x", sum)
+}
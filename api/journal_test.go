@@ -0,0 +1,137 @@
+package api
+
+import (
+	"github.com/leanovate/mite-go/domain"
+	"path/filepath"
+	"testing"
+)
+
+func newRow(t *testing.T, minutes int) domain.TimeEntry {
+	return domain.TimeEntry{
+		Date:      date(t, "2021-03-04"),
+		Minutes:   domain.NewMinutes(minutes),
+		Note:      "review",
+		ProjectId: domain.NewProjectId(2),
+		ServiceId: domain.NewServiceId(3),
+	}
+}
+
+// inMite returns the entry as mite created it from the row
+func inMite(row domain.TimeEntry, id int) *domain.TimeEntry {
+	row.Id = domain.NewTimeEntryId(id)
+	return &row
+}
+
+func openJournal(t *testing.T) (*Journal, string) {
+	excelFilePath := filepath.Join(t.TempDir(), "timesheet.xlsx")
+
+	journal, err := OpenJournal(excelFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = journal.Close() })
+
+	return journal, excelFilePath
+}
+
+func createPlan(rows ...domain.TimeEntry) *Plan {
+	plan := &Plan{}
+	for _, row := range rows {
+		plan.add(Operation{Action: ActionCreate, Date: row.Date.String(), entry: row})
+	}
+
+	return plan
+}
+
+func TestJournalReconcileFinishesUnconfirmedCreates(t *testing.T) {
+	journal, excelFilePath := openJournal(t)
+
+	// two identical rows, both sent but never confirmed
+	plan := createPlan(newRow(t, 60), newRow(t, 60), newRow(t, 30))
+	for _, op := range plan.Operations {
+		if err := journal.Begin(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := journal.Reconcile(plan, []*domain.TimeEntry{inMite(newRow(t, 60), 11), inMite(newRow(t, 60), 12)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second, third := plan.Operations[0], plan.Operations[1], plan.Operations[2]
+
+	if !journal.Done(first) || !journal.Done(second) {
+		t.Fatal("expected the creates with an entry in mite to be done")
+	}
+
+	if journal.records[first.key].CreatedId == journal.records[second.key].CreatedId {
+		t.Errorf("expected the identical creates to claim different entries, both got %s", journal.records[first.key].CreatedId)
+	}
+
+	if journal.Done(third) {
+		t.Error("expected the create without an entry in mite to stay pending")
+	}
+
+	// the outcome survives the interrupted push
+	reopened, err := OpenJournal(excelFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = reopened.Close() }()
+
+	if !reopened.Done(first) || !reopened.Done(second) || reopened.Done(third) {
+		t.Error("expected the reopened journal to hold the reconciled creates")
+	}
+}
+
+func TestJournalReconcileSkipsClaimedEntries(t *testing.T) {
+	journal, _ := openJournal(t)
+
+	plan := createPlan(newRow(t, 60), newRow(t, 60))
+
+	// the first create was confirmed with entry 11, the second one never
+	if err := journal.Finish(plan.Operations[0], domain.NewTimeEntryId(11)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := journal.Begin(plan.Operations[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	err := journal.Reconcile(plan, []*domain.TimeEntry{inMite(newRow(t, 60), 11)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if journal.Done(plan.Operations[1]) {
+		t.Error("expected the create to stay pending, its only candidate belongs to another create")
+	}
+}
+
+func TestJournalReconcileOnlySettlesPendingCreates(t *testing.T) {
+	journal, _ := openJournal(t)
+
+	unsent := createPlan(newRow(t, 60))
+
+	update := &Plan{}
+	update.add(Operation{Action: ActionUpdate, EntryId: domain.NewTimeEntryId(5), entry: *inMite(newRow(t, 60), 5)})
+
+	if err := journal.Begin(update.Operations[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates := []*domain.TimeEntry{inMite(newRow(t, 60), 11)}
+
+	for _, plan := range []*Plan{unsent, update} {
+		if err := journal.Reconcile(plan, candidates); err != nil {
+			t.Fatal(err)
+		}
+
+		if journal.Done(plan.Operations[0]) {
+			t.Errorf("expected the %s to be left alone", plan.Operations[0].Action)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"mighty/reference"
+	"strconv"
+	"time"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change is a single field of an entry as it is in mite and as it will be after the push
+type Change struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Operation is a single write call to mite
+type Operation struct {
+	Action  Action             `json:"action"`
+	EntryId domain.TimeEntryId `json:"entryId,omitempty"`
+	Date    string             `json:"date"`
+	Project string             `json:"project"`
+	Service string             `json:"service"`
+	Changes []Change           `json:"changes"`
+
+	entry domain.TimeEntry
+	// key identifies the operation in the journal
+	key string
+	// updatedAt is the last change in mite the update or delete is based on
+	updatedAt time.Time
+}
+
+// Plan lists the operations a push will do, in the order they will be done
+type Plan struct {
+	Operations []Operation `json:"operations"`
+}
+
+// PlanEntries decides for every entry whether it has to be created, updated or deleted in mite. Updates and
+// deletes are diffed against current, entries missing there are fetched from mite one by one. Updates of entries
+// which were deleted in mite meanwhile are returned apart from the plan. While mite is unreachable the entries missing
+// in current are left out, they are planned by a push which can look them up.
+func (c *Client) PlanEntries(entries []domain.TimeEntry, current []*domain.TimeEntry, offline bool) (*Plan, []domain.TimeEntry, error) {
+	currentById := make(map[domain.TimeEntryId]*domain.TimeEntry, len(current))
+	for _, entry := range current {
+		currentById[entry.Id] = entry
+	}
+
+	// an empty plan is printed as empty list, not as null
+	plan := &Plan{Operations: []Operation{}}
+
+	var deleted []domain.TimeEntry
+
+	for _, entry := range entries {
+		// a deletion only needs the id
+		deletion := entry.Id != 0 && entry.Minutes.Value() == 0
+
+		if !deletion && (entry.ProjectId < 1 || entry.ServiceId < 1) {
+			return nil, nil, fmt.Errorf("[%s] entry of %s has no service id or project id", entry.Id, entry.Date)
+		}
+
+		op := Operation{
+			EntryId: entry.Id,
+			Date:    entry.Date.String(),
+			Project: reference.DisplayName(entry.CustomerName, entry.ProjectName),
+			Service: entry.ServiceName,
+			entry:   entry,
+		}
+
+		if entry.Id == 0 {
+			op.Action = ActionCreate
+			op.Changes = diffEntries(nil, &entry)
+			plan.add(op)
+
+			continue
+		}
+
+		before, ok := currentById[entry.Id]
+		if !ok && offline {
+			log.Warnf("[%s] entry of %s is unknown since the last sync, it is pushed once mite is reachable", entry.Id, entry.Date)
+			continue
+		}
+
+		if !ok {
+			var err error
+
+			c.limiter.Wait()
+
+			before, err = c.timeEntry(entry.Id)
+			if isNotFound(err) {
+				if !deletion {
+					deleted = append(deleted, entry)
+				}
+
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if before.Locked {
+			// mite rejects changes of locked entries, they are only unlocked in mite itself
+			log.Warnf("[%s] entry of %s is locked in mite, skipping it", entry.Id, entry.Date)
+			continue
+		}
+
+		op.updatedAt = before.UpdatedAt
+
+		if deletion {
+			op.Action = ActionDelete
+			op.Changes = diffEntries(before, nil)
+		} else {
+			op.Action = ActionUpdate
+			op.Changes = diffEntries(before, &entry)
+
+			if len(op.Changes) == 0 {
+				log.Debugf("[%s] entry is unchanged, skipping it", entry.Id)
+				continue
+			}
+		}
+
+		plan.add(op)
+	}
+
+	return plan, deleted, nil
+}
+
+func (p *Plan) add(op Operation) {
+	key := fingerprint(op.Action, op.EntryId, &op.entry)
+	occurrence := 1
+
+	// identical new rows are told apart by their position
+	for _, other := range p.Operations {
+		if fingerprint(other.Action, other.EntryId, &other.entry) == key {
+			occurrence++
+		}
+	}
+
+	op.key = fmt.Sprintf("%s#%d", key, occurrence)
+	p.Operations = append(p.Operations, op)
+}
+
+// Count returns the number of operations with the given action
+func (p *Plan) Count(action Action) int {
+	count := 0
+
+	for _, op := range p.Operations {
+		if op.Action == action {
+			count++
+		}
+	}
+
+	return count
+}
+
+// WriteText writes the plan in a human readable form
+func (p *Plan) WriteText(w io.Writer) error {
+	symbols := map[Action]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}
+
+	for _, op := range p.Operations {
+		id := "new"
+		if op.EntryId != 0 {
+			id = op.EntryId.String()
+		}
+
+		_, err := fmt.Fprintf(w, "  %s %s [%s] %s | %s | %s\n", symbols[op.Action], op.Action, id, op.Date, op.Project, op.Service)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range op.Changes {
+			_, err = fmt.Fprintf(w, "      %-8s %q => %q\n", change.Field+":", change.Before, change.After)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\nPlan: %d to create, %d to update, %d to delete.\n",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionDelete))
+
+	return err
+}
+
+// WriteJSON writes the plan as indented json
+func (p *Plan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(p)
+}
+
+// diffEntries returns the changed fields between both entries, a nil entry stands for a non existing one
+func diffEntries(before, after *domain.TimeEntry) []Change {
+	fields := func(entry *domain.TimeEntry) []string {
+		if entry == nil {
+			return []string{"", "", "", "", "", ""}
+		}
+
+		return []string{entry.Date.String(), entry.Minutes.String(), entry.Note, strconv.FormatBool(entry.Billable),
+			reference.DisplayName(entry.CustomerName, entry.ProjectName), entry.ServiceName}
+	}
+
+	ids := func(entry *domain.TimeEntry) []int {
+		if entry == nil {
+			return []int{0, 0}
+		}
+
+		return []int{int(entry.ProjectId), int(entry.ServiceId)}
+	}
+
+	names := []string{"date", "minutes", "note", "billable", "project", "service"}
+	b, a := fields(before), fields(after)
+	bIds, aIds := ids(before), ids(after)
+
+	var changes []Change
+
+	for i, name := range names {
+		changed := b[i] != a[i]
+
+		// project and service names may differ in case, their ids decide
+		if i >= 4 {
+			changed = bIds[i-4] != aIds[i-4]
+		}
+
+		if changed {
+			changes = append(changes, Change{Field: name, Before: b[i], After: a[i]})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// job is a unit of work of the pool, the returned message is logged once all preceding jobs are logged
+type job func() (string, error)
+
+type jobResult struct {
+	message string
+	err     error
+}
+
+// runJobs runs the jobs on at most workers goroutines. The results are handed to report strictly in the
+// order of the jobs, so the output stays the same no matter in which order the jobs finish.
+func runJobs(workers int, jobs []job, report func(index int, message string, err error)) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]chan jobResult, len(jobs))
+	for i := range results {
+		results[i] = make(chan jobResult, 1)
+	}
+
+	queue := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers && w < len(jobs); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range queue {
+				message, err := jobs[i]()
+				results[i] <- jobResult{message, err}
+			}
+		}()
+	}
+
+	go func() {
+		for i := range jobs {
+			queue <- i
+		}
+		close(queue)
+	}()
+
+	for i := range jobs {
+		result := <-results[i]
+		report(i, result.message, result.err)
+	}
+
+	wg.Wait()
+}
+
+// OperationError is a failed operation of a push
+type OperationError struct {
+	Operation Operation
+	Err       error
+}
+
+func (e OperationError) Error() string {
+	return fmt.Sprintf("%s %s | %s | %s: %v", e.Operation.Action, e.Operation.Date, e.Operation.Project, e.Operation.Service, e.Err)
+}
+
+// PushError aggregates the failed operations of a push
+type PushError struct {
+	Total    int
+	Failures []OperationError
+}
+
+func (e *PushError) Error() string {
+	lines := make([]string, 0, len(e.Failures)+1)
+	lines = append(lines, fmt.Sprintf("%d of %d operations failed", len(e.Failures), e.Total))
+
+	for _, f := range e.Failures {
+		lines = append(lines, " - "+f.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// limiter is a token bucket allowing rate calls per second with bursts of up to burst calls
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a call is allowed, a limiter without rate never blocks
+func (l *limiter) Wait() {
+	if l == nil || l.rate <= 0 {
+		return
+	}
+
+	for {
+		l.mu.Lock()
+
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		l.last = now
+
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+
+			return
+		}
+
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		time.Sleep(wait)
+	}
+}
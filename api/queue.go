@@ -0,0 +1,355 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"mighty/atomicfile"
+	"net/url"
+	"os"
+	"time"
+)
+
+// QueuedOperation is an operation of a push which couldn't reach mite, along with the body it sends
+type QueuedOperation struct {
+	Operation
+	Key     string            `json:"key"`
+	Payload *timeEntryRequest `json:"payload,omitempty"`
+	// BaseUpdatedAt is the last change in mite an update or delete is based on, the replay skips entries which
+	// were changed in mite since
+	BaseUpdatedAt time.Time `json:"baseUpdatedAt,omitempty"`
+}
+
+type queue struct {
+	QueuedAt   time.Time         `json:"queuedAt"`
+	Operations []QueuedOperation `json:"operations"`
+}
+
+// QueuePath returns the path of the offline queue belonging to the given workbook
+func QueuePath(excelFilePath string) string {
+	return excelFilePath + ".queue"
+}
+
+// QueueExists reports whether operations of the workbook wait for mite to be reachable again
+func QueueExists(excelFilePath string) bool {
+	_, err := os.Stat(QueuePath(excelFilePath))
+	return err == nil
+}
+
+// IsUnreachable reports whether the call failed without an answer of mite, e.g. without network
+func IsUnreachable(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// QueuePlan stores the operations of the plan to be replayed once mite is reachable. The plan covers all local
+// changes of the workbook between the dates, so it supersedes the queued operations of these dates and of the
+// entries it changes, the queued operations of other dates are kept.
+func QueuePlan(excelFilePath string, plan *Plan, from, to domain.LocalDate) error {
+	queued, err := ReadQueue(excelFilePath)
+	if err != nil {
+		return err
+	}
+
+	superseded := make(map[string]bool, len(plan.Operations))
+	changed := make(map[domain.TimeEntryId]bool, len(plan.Operations))
+
+	for _, op := range plan.Operations {
+		superseded[op.key] = true
+		if op.EntryId != 0 {
+			changed[op.EntryId] = true
+		}
+	}
+
+	q := queue{QueuedAt: time.Now(), Operations: make([]QueuedOperation, 0, len(queued)+len(plan.Operations))}
+
+	for _, op := range queued {
+		inPlan := op.Date >= from.String() && op.Date <= to.String()
+		if !inPlan && !superseded[op.key] && !changed[op.EntryId] {
+			q.Operations = append(q.Operations, op)
+		}
+	}
+
+	for _, op := range plan.Operations {
+		queued := QueuedOperation{Operation: op, Key: op.key, BaseUpdatedAt: op.updatedAt}
+		if op.Action != ActionDelete {
+			queued.Payload = newTimeEntryRequest(&op.entry)
+		}
+
+		q.Operations = append(q.Operations, queued)
+	}
+
+	if len(q.Operations) == 0 {
+		return RemoveQueue(excelFilePath)
+	}
+
+	return writeQueue(excelFilePath, &q)
+}
+
+// ReadQueue returns the queued operations of the workbook in the order they are replayed
+func ReadQueue(excelFilePath string) ([]QueuedOperation, error) {
+	b, err := ioutil.ReadFile(QueuePath(excelFilePath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	q := queue{}
+	if err = json.Unmarshal(b, &q); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", QueuePath(excelFilePath), err)
+	}
+
+	for i := range q.Operations {
+		q.Operations[i].restore()
+	}
+
+	return q.Operations, nil
+}
+
+// QueuedPlan returns the queued operations as plan, e.g. to print them
+func QueuedPlan(excelFilePath string) (*Plan, error) {
+	queued, err := ReadQueue(excelFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Operations: make([]Operation, 0, len(queued))}
+	for _, q := range queued {
+		plan.Operations = append(plan.Operations, q.Operation)
+	}
+
+	return plan, nil
+}
+
+// RemoveQueue deletes the offline queue of the workbook
+func RemoveQueue(excelFilePath string) error {
+	err := os.Remove(QueuePath(excelFilePath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// FlushQueue replays the queued operations one by one in their order and returns the entries it created.
+// Updates and deletes of entries which were changed or locked in mite in the meantime are skipped, the next sync
+// merges them instead. Operations are recorded in the journal like a push. If mite becomes unreachable or an entry
+// can't be looked up the rest stays queued. Creates of an interrupted replay are reconciled like a resumed push,
+// known are the entries of the workbook.
+func (c *Client) FlushQueue(excelFilePath string, known map[domain.TimeEntryId]bool) ([]*domain.TimeEntry, error) {
+	queued, err := ReadQueue(excelFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(queued) == 0 {
+		return nil, RemoveQueue(excelFilePath)
+	}
+
+	log.Infof("Replaying %d queued operations", len(queued))
+
+	journal, err := OpenJournal(excelFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = journal.Close() }()
+
+	err = c.reconcileQueue(journal, queued, known)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, q := range queued {
+		if q.Action != ActionCreate {
+			c.limiter.Wait()
+
+			current, err := c.timeEntry(q.EntryId)
+
+			switch {
+			case isNotFound(err):
+				log.Warnf("Skipping the queued %s of [%s], it is gone from mite", q.Action, q.EntryId)
+				continue
+			case err != nil:
+				// without knowing the entry in mite the operation can't be checked, it waits for the next replay
+				return nil, requeue(excelFilePath, queued[i:], err)
+			case current.Locked:
+				log.Warnf("Skipping the queued %s of [%s], it is locked in mite", q.Action, q.EntryId)
+				continue
+			case !current.UpdatedAt.Equal(q.BaseUpdatedAt):
+				log.Warnf("Skipping the queued %s of [%s], it was changed in mite, the next sync merges it", q.Action, q.EntryId)
+				continue
+			}
+		}
+
+		message, err := c.sendOperation(q.Operation, journal)
+		if IsUnreachable(err) {
+			return nil, requeue(excelFilePath, queued[i:], err)
+		}
+
+		if err != nil {
+			// the change stays in the workbook, the next sync validates and pushes it again
+			log.Errorf("Failed to %s", OperationError{q.Operation, err})
+			continue
+		}
+
+		log.Info(message)
+	}
+
+	err = RemoveQueue(excelFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.createdEntries(journal, queued)
+}
+
+// createdEntries fetches the entries the queued creates led to, as recorded in the journal
+func (c *Client) createdEntries(journal *Journal, queued []QueuedOperation) ([]*domain.TimeEntry, error) {
+	created := make(map[domain.TimeEntryId]bool)
+	from, to := "", ""
+
+	for _, q := range queued {
+		record := journal.records[q.key]
+		if q.Action != ActionCreate || record.Status != JournalDone || record.CreatedId == 0 {
+			continue
+		}
+
+		created[record.CreatedId] = true
+
+		if from == "" || q.Date < from {
+			from = q.Date
+		}
+
+		if q.Date > to {
+			to = q.Date
+		}
+	}
+
+	if len(created) == 0 {
+		return nil, nil
+	}
+
+	fromDate, err := domain.ParseLocalDate(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toDate, err := domain.ParseLocalDate(to)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := c.FetchEntriesBetween(fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*domain.TimeEntry
+
+	for _, entry := range remote {
+		if created[entry.Id] {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// reconcileQueue settles the queued creates an interrupted replay sent without confirmation, the candidates are
+// the entries of their dates in mite which aren't known
+func (c *Client) reconcileQueue(journal *Journal, queued []QueuedOperation, known map[domain.TimeEntryId]bool) error {
+	plan := &Plan{}
+	from, to := "", ""
+
+	for _, q := range queued {
+		if q.Action != ActionCreate || journal.records[q.key].Status != JournalPending {
+			continue
+		}
+
+		plan.Operations = append(plan.Operations, q.Operation)
+
+		if from == "" || q.Date < from {
+			from = q.Date
+		}
+
+		if q.Date > to {
+			to = q.Date
+		}
+	}
+
+	if len(plan.Operations) == 0 {
+		return nil
+	}
+
+	fromDate, err := domain.ParseLocalDate(from)
+	if err != nil {
+		return err
+	}
+
+	toDate, err := domain.ParseLocalDate(to)
+	if err != nil {
+		return err
+	}
+
+	remote, err := c.FetchEntriesBetween(fromDate, toDate)
+	if err != nil {
+		return err
+	}
+
+	var candidates []*domain.TimeEntry
+
+	for _, entry := range remote {
+		if !known[entry.Id] {
+			candidates = append(candidates, entry)
+		}
+	}
+
+	return journal.Reconcile(plan, candidates)
+}
+
+// requeue keeps the operations which weren't replayed
+func requeue(excelFilePath string, rest []QueuedOperation, cause error) error {
+	err := writeQueue(excelFilePath, &queue{QueuedAt: time.Now(), Operations: rest})
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%d operations stay queued: %w", len(rest), cause)
+}
+
+// restore sets the unexported fields of the operation, which aren't part of the stored json
+func (q *QueuedOperation) restore() {
+	q.key = q.Key
+	q.updatedAt = q.BaseUpdatedAt
+	q.entry = domain.TimeEntry{Id: q.EntryId}
+
+	if q.Payload != nil {
+		p := q.Payload.TimeEntry
+		date, _ := domain.ParseLocalDate(p.Date)
+
+		q.entry.Date = date
+		q.entry.Minutes = domain.NewMinutes(p.Minutes)
+		q.entry.Note = p.Note
+		q.entry.Billable = p.Billable
+		q.entry.ProjectId = domain.NewProjectId(p.ProjectId)
+		q.entry.ServiceId = domain.NewServiceId(p.ServiceId)
+	}
+
+	q.entry.ProjectName = q.Project
+	q.entry.ServiceName = q.Service
+}
+
+func writeQueue(excelFilePath string, q *queue) error {
+	b, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(QueuePath(excelFilePath), b, 0600)
+}
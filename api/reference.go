@@ -0,0 +1,88 @@
+package api
+
+import (
+	"github.com/leanovate/mite-go/domain"
+	"mighty/reference"
+)
+
+type serviceResponse struct {
+	Service struct {
+		Id       int    `json:"id"`
+		Name     string `json:"name"`
+		Billable bool   `json:"billable"`
+	} `json:"service"`
+}
+
+type projectResponse struct {
+	Project struct {
+		Id           int    `json:"id"`
+		Name         string `json:"name"`
+		CustomerId   int    `json:"customer_id"`
+		CustomerName string `json:"customer_name"`
+	} `json:"project"`
+}
+
+// fetchServices fetches the active and archived services directly, the mite api library drops their billable flag
+// and only lists the active ones
+func (c *Client) fetchServices() ([]reference.Service, error) {
+	var services []reference.Service
+
+	for _, archived := range []bool{false, true} {
+		var srs []serviceResponse
+
+		c.limiter.Wait()
+
+		err := c.rest.get(archivedResource("/services", archived), nil, &srs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sr := range srs {
+			services = append(services, reference.Service{
+				Id:       domain.NewServiceId(sr.Service.Id),
+				Name:     sr.Service.Name,
+				Billable: sr.Service.Billable,
+				Archived: archived,
+			})
+		}
+	}
+
+	return services, nil
+}
+
+// fetchProjects fetches the active and archived projects directly, the mite api library drops their customer
+// and only lists the active ones
+func (c *Client) fetchProjects() ([]reference.Project, error) {
+	var projects []reference.Project
+
+	for _, archived := range []bool{false, true} {
+		var prs []projectResponse
+
+		c.limiter.Wait()
+
+		err := c.rest.get(archivedResource("/projects", archived), nil, &prs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pr := range prs {
+			projects = append(projects, reference.Project{
+				Id:           domain.NewProjectId(pr.Project.Id),
+				Name:         pr.Project.Name,
+				CustomerId:   domain.NewCustomerId(pr.Project.CustomerId),
+				CustomerName: pr.Project.CustomerName,
+				Archived:     archived,
+			})
+		}
+	}
+
+	return projects, nil
+}
+
+func archivedResource(resource string, archived bool) string {
+	if archived {
+		return resource + "/archived.json"
+	}
+
+	return resource + ".json"
+}
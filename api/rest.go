@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const restUserAgent = "mighty (+github.com/foryouandyourcustomers/mighty)"
+
+// rest calls the mite api directly. The mite api library neither covers all fields, e.g. billable, nor lets its
+// http.Client be configured, e.g. to retry transient failures.
+type rest struct {
+	base   *url.URL
+	key    string
+	client *http.Client
+}
+
+// notFoundError is returned for resources which don't exist in mite, e.g. a deleted entry
+type notFoundError struct {
+	error
+}
+
+func isNotFound(err error) bool {
+	return errors.As(err, &notFoundError{})
+}
+
+func newRest(baseUrl, key string, transport http.RoundTripper) (*rest, error) {
+	base, err := url.Parse(baseUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rest{base: base, key: key, client: &http.Client{Transport: transport}}, nil
+}
+
+func (r *rest) get(resource string, query url.Values, result interface{}) error {
+	return r.do(http.MethodGet, resource, query, nil, result)
+}
+
+func (r *rest) post(resource string, body, result interface{}) error {
+	return r.do(http.MethodPost, resource, nil, body, result)
+}
+
+func (r *rest) patch(resource string, body, result interface{}) error {
+	return r.do(http.MethodPatch, resource, nil, body, result)
+}
+
+func (r *rest) delete(resource string, result interface{}) error {
+	return r.do(http.MethodDelete, resource, nil, nil, result)
+}
+
+func (r *rest) do(method, resource string, query url.Values, body, result interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		payload = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, r.base.ResolveReference(&url.URL{Path: resource, RawQuery: query.Encode()}).String(), payload)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	req.Header.Add("User-Agent", restUserAgent)
+	req.Header.Add("X-MiteApiKey", r.key)
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 {
+		msg := struct {
+			Error string `json:"error"`
+		}{}
+		if json.NewDecoder(res.Body).Decode(&msg) != nil || msg.Error == "" {
+			msg.Error = res.Status
+		}
+
+		err = fmt.Errorf("failed to %s %s: %s", method, resource, msg.Error)
+		if res.StatusCode == http.StatusNotFound {
+			return notFoundError{err}
+		}
+
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(result)
+}
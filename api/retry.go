@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const retryBaseWait = 500 * time.Millisecond
+
+// retryTransport retries calls to mite failing with transient errors. It sits below the http.Client of the rest
+// client, where the status codes and the Retry-After header are still visible.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+	maxWait time.Duration
+	limiter *limiter
+}
+
+func newRetryTransport(retries int, maxWait time.Duration, limiter *limiter) *retryTransport {
+	return &retryTransport{
+		next:    http.DefaultTransport,
+		retries: retries,
+		maxWait: maxWait,
+		limiter: limiter,
+	}
+}
+
+// RoundTrip sends a clone of the request per attempt, a RoundTripper must not modify the caller's request
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		send := req.Clone(req.Context())
+
+		if attempt > 0 {
+			if req.Body != nil {
+				if req.GetBody == nil {
+					return nil, errors.New("unable to retry a request without a rewindable body")
+				}
+
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+
+				send.Body = body
+			}
+
+			t.limiter.Wait()
+		}
+
+		res, err := t.next.RoundTrip(send)
+
+		wait, retry := t.backoff(req, res, err, attempt)
+		if !retry {
+			return res, err
+		}
+
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = res.Status
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		log.Warnf("%s %s failed with %s, retrying in %s (%d/%d)", req.Method, req.URL.Path, reason, wait.Round(time.Millisecond), attempt+1, t.retries)
+		time.Sleep(wait)
+	}
+}
+
+// backoff decides whether the failed attempt is retried and how long to wait before
+func (t *retryTransport) backoff(req *http.Request, res *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= t.retries {
+		return 0, false
+	}
+
+	wait := t.exponential(attempt)
+
+	switch {
+	case err != nil:
+		// a create which never reached mite is safe to repeat, any other failure might have created the entry
+		if !idempotent(req.Method) && !neverSent(err) {
+			return 0, false
+		}
+	case res.StatusCode == http.StatusTooManyRequests:
+		// mite rejected the call without processing it, which makes it safe for every method
+		if after, ok := retryAfter(res); ok {
+			if after > t.maxWait {
+				log.Warnf("mite asks to retry %s %s in %s, which exceeds the max wait of %s", req.Method, req.URL.Path, after, t.maxWait)
+				return 0, false
+			}
+
+			wait = after
+		}
+	case res.StatusCode >= 500:
+		if !idempotent(req.Method) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	if wait > t.maxWait {
+		wait = t.maxWait
+	}
+
+	return wait, true
+}
+
+// exponential returns the doubling wait of the attempt with jitter between the half and the full wait
+func (t *retryTransport) exponential(attempt int) time.Duration {
+	wait := retryBaseWait << uint(attempt)
+	if wait <= 0 || wait > t.maxWait {
+		wait = t.maxWait
+	}
+
+	half := int64(wait / 2)
+
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// neverSent reports whether the request failed before a connection to mite was established
+func neverSent(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+
+	return errors.As(err, &dnsErr)
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as http date
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	header := res.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+
+		return 0, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func response(status int, retryAfter string) *http.Response {
+	res := &http.Response{StatusCode: status, Status: http.StatusText(status), Header: http.Header{}}
+	if retryAfter != "" {
+		res.Header.Set("Retry-After", retryAfter)
+	}
+
+	return res
+}
+
+func TestRetryTransportBackoff(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}
+
+	tests := []struct {
+		name    string
+		method  string
+		res     *http.Response
+		err     error
+		attempt int
+		retry   bool
+		// wait is the exact wait, zero for the exponential one
+		wait time.Duration
+	}{
+		{"server error of a get", http.MethodGet, response(http.StatusServiceUnavailable, ""), nil, 0, true, 0},
+		{"server error of a patch", http.MethodPatch, response(http.StatusBadGateway, ""), nil, 1, true, 0},
+		{"server error of a create", http.MethodPost, response(http.StatusInternalServerError, ""), nil, 0, false, 0},
+		{"rate limited create", http.MethodPost, response(http.StatusTooManyRequests, "2"), nil, 0, true, 2 * time.Second},
+		{"rate limited without retry-after", http.MethodGet, response(http.StatusTooManyRequests, ""), nil, 0, true, 0},
+		{"rate limited beyond the max wait", http.MethodGet, response(http.StatusTooManyRequests, "120"), nil, 0, false, 0},
+		{"rate limited until a date", http.MethodGet, response(http.StatusTooManyRequests, "Mon, 02 Jan 2006 15:04:05 GMT"), nil, 0, true, 0},
+		{"not found", http.MethodGet, response(http.StatusNotFound, ""), nil, 0, false, 0},
+		{"success", http.MethodGet, response(http.StatusOK, ""), nil, 0, false, 0},
+		{"network error of a get", http.MethodGet, nil, readErr, 0, true, 0},
+		{"create never sent", http.MethodPost, nil, dialErr, 0, true, 0},
+		{"create failed after it was sent", http.MethodPost, nil, readErr, 0, false, 0},
+		{"retries used up", http.MethodGet, response(http.StatusServiceUnavailable, ""), nil, 3, false, 0},
+	}
+
+	transport := newRetryTransport(3, 30*time.Second, nil)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "https://example.mite.yo.lk/time_entries.json", nil)
+
+			wait, retry := transport.backoff(req, test.res, test.err, test.attempt)
+			if retry != test.retry {
+				t.Fatalf("expected retry %t, got %t", test.retry, retry)
+			}
+
+			if !retry {
+				return
+			}
+
+			if test.wait != 0 && wait != test.wait {
+				t.Errorf("expected a wait of %s, got %s", test.wait, wait)
+			}
+
+			if wait < 0 || wait > transport.maxWait {
+				t.Errorf("expected a wait up to %s, got %s", transport.maxWait, wait)
+			}
+		})
+	}
+}
+
+func TestRetryTransportExponentialBackoff(t *testing.T) {
+	transport := newRetryTransport(10, 5*time.Second, nil)
+
+	for attempt := 0; attempt < 10; attempt++ {
+		full := retryBaseWait << uint(attempt)
+		if full > transport.maxWait {
+			full = transport.maxWait
+		}
+
+		wait := transport.exponential(attempt)
+		if wait < full/2 || wait > full {
+			t.Errorf("attempt %d: expected a wait between %s and %s, got %s", attempt, full/2, full, wait)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for header, expected := range map[string]time.Duration{"": 0, "0": 0, "7": 7 * time.Second, "soon": 0} {
+		wait, _ := retryAfter(response(http.StatusTooManyRequests, header))
+		if wait != expected {
+			t.Errorf("Retry-After %s: expected %s, got %s", strconv.Quote(header), expected, wait)
+		}
+	}
+}
+
+func TestRetryTransportResendsTheBodyWithoutChangingTheRequest(t *testing.T) {
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPatch, server.URL, bytes.NewBufferString(`{"minutes":60}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := req.Body
+
+	res, err := newRetryTransport(1, time.Millisecond, nil).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = res.Body.Close()
+
+	if len(bodies) != 2 || bodies[0] != bodies[1] {
+		t.Errorf("expected the body to be sent twice, got %q", bodies)
+	}
+
+	if req.Body != body {
+		t.Error("the body of the caller's request was replaced")
+	}
+}
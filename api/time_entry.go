@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	"net/url"
+	"time"
+)
+
+// timeEntryRequest is the body of a create or edit. Unlike domain.TimeEntryCommand it carries the billable flag
+// and always sends the note, so clearing a note in the timesheet clears it in mite as well.
+type timeEntryRequest struct {
+	TimeEntry struct {
+		Date      string `json:"date_at"`
+		Minutes   int    `json:"minutes"`
+		Note      string `json:"note"`
+		Billable  bool   `json:"billable"`
+		ProjectId int    `json:"project_id"`
+		ServiceId int    `json:"service_id"`
+	} `json:"time_entry"`
+}
+
+type timeEntryResponse struct {
+	TimeEntry struct {
+		Id           int       `json:"id"`
+		Date         string    `json:"date_at"`
+		Minutes      int       `json:"minutes"`
+		Note         string    `json:"note"`
+		Billable     bool      `json:"billable"`
+		Locked       bool      `json:"locked"`
+		ProjectId    int       `json:"project_id"`
+		ProjectName  string    `json:"project_name"`
+		CustomerId   int       `json:"customer_id"`
+		CustomerName string    `json:"customer_name"`
+		ServiceId    int       `json:"service_id"`
+		ServiceName  string    `json:"service_name"`
+		CreatedAt    time.Time `json:"created_at"`
+		UpdatedAt    time.Time `json:"updated_at"`
+	} `json:"time_entry"`
+}
+
+func newTimeEntryRequest(entry *domain.TimeEntry) *timeEntryRequest {
+	r := &timeEntryRequest{}
+	r.TimeEntry.Date = entry.Date.String()
+	r.TimeEntry.Minutes = entry.Minutes.Value()
+	r.TimeEntry.Note = entry.Note
+	r.TimeEntry.Billable = entry.Billable
+	r.TimeEntry.ProjectId = int(entry.ProjectId)
+	r.TimeEntry.ServiceId = int(entry.ServiceId)
+
+	return r
+}
+
+func (r *timeEntryResponse) toTimeEntry() (*domain.TimeEntry, error) {
+	date, err := domain.ParseLocalDate(r.TimeEntry.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TimeEntry{
+		Id:           domain.NewTimeEntryId(r.TimeEntry.Id),
+		Date:         date,
+		Minutes:      domain.NewMinutes(r.TimeEntry.Minutes),
+		Note:         r.TimeEntry.Note,
+		Billable:     r.TimeEntry.Billable,
+		Locked:       r.TimeEntry.Locked,
+		ProjectId:    domain.NewProjectId(r.TimeEntry.ProjectId),
+		ProjectName:  r.TimeEntry.ProjectName,
+		CustomerId:   domain.NewCustomerId(r.TimeEntry.CustomerId),
+		CustomerName: r.TimeEntry.CustomerName,
+		ServiceId:    domain.NewServiceId(r.TimeEntry.ServiceId),
+		ServiceName:  r.TimeEntry.ServiceName,
+		CreatedAt:    r.TimeEntry.CreatedAt.UTC(),
+		UpdatedAt:    r.TimeEntry.UpdatedAt.UTC(),
+	}, nil
+}
+
+// timeEntries fetches the entries of the current user from the given dates, both inclusive
+func (c *Client) timeEntries(from, to domain.LocalDate) ([]*domain.TimeEntry, error) {
+	var res []timeEntryResponse
+
+	query := url.Values{}
+	query.Add("user_id", domain.CurrentUser.String())
+	query.Add("from", from.String())
+	query.Add("to", to.String())
+
+	err := c.rest.get("/time_entries.json", query, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*domain.TimeEntry, 0, len(res))
+
+	for _, r := range res {
+		entry, err := r.toTimeEntry()
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// timeEntry fetches a single entry, see isNotFound for entries deleted in mite
+func (c *Client) timeEntry(id domain.TimeEntryId) (*domain.TimeEntry, error) {
+	res := timeEntryResponse{}
+
+	err := c.rest.get(fmt.Sprintf("/time_entries/%s.json", id), nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.toTimeEntry()
+}
+
+// createTimeEntry creates the entry for the current user and returns its id and update time
+func (c *Client) createTimeEntry(entry *domain.TimeEntry) (domain.TimeEntryId, time.Time, error) {
+	res := timeEntryResponse{}
+
+	err := c.rest.post("/time_entries.json", newTimeEntryRequest(entry), &res)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return domain.NewTimeEntryId(res.TimeEntry.Id), res.TimeEntry.UpdatedAt.UTC(), nil
+}
+
+func (c *Client) editTimeEntry(entry *domain.TimeEntry) error {
+	return c.rest.patch(fmt.Sprintf("/time_entries/%s.json", entry.Id), newTimeEntryRequest(entry), nil)
+}
+
+func (c *Client) deleteTimeEntry(id domain.TimeEntryId) error {
+	return c.rest.delete(fmt.Sprintf("/time_entries/%s.json", id), nil)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	"time"
+)
+
+type trackerResponse struct {
+	Tracker struct {
+		TrackingTimeEntry *struct {
+			Id      int       `json:"id"`
+			Minutes int       `json:"minutes"`
+			Since   time.Time `json:"since"`
+		} `json:"tracking_time_entry"`
+		StoppedTimeEntry *struct {
+			Id      int `json:"id"`
+			Minutes int `json:"minutes"`
+		} `json:"stopped_time_entry"`
+	} `json:"tracker"`
+}
+
+func (r *trackerResponse) tracking() *domain.TrackingTimeEntry {
+	if r.Tracker.TrackingTimeEntry == nil {
+		return nil
+	}
+
+	return &domain.TrackingTimeEntry{
+		Id:      domain.NewTimeEntryId(r.Tracker.TrackingTimeEntry.Id),
+		Minutes: domain.NewMinutes(r.Tracker.TrackingTimeEntry.Minutes),
+		Since:   r.Tracker.TrackingTimeEntry.Since.UTC(),
+	}
+}
+
+func (r *trackerResponse) stopped() *domain.StoppedTimeEntry {
+	if r.Tracker.StoppedTimeEntry == nil {
+		return nil
+	}
+
+	return &domain.StoppedTimeEntry{
+		Id:      domain.NewTimeEntryId(r.Tracker.StoppedTimeEntry.Id),
+		Minutes: domain.NewMinutes(r.Tracker.StoppedTimeEntry.Minutes),
+	}
+}
+
+func (c *Client) tracker() (*domain.TrackingTimeEntry, error) {
+	res := trackerResponse{}
+
+	err := c.rest.get("/tracker.json", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.tracking(), nil
+}
+
+// Tracker returns the running tracker of the current user along with its entry, both are nil if no tracker runs
+func (c *Client) Tracker() (*domain.TrackingTimeEntry, *domain.TimeEntry, error) {
+	c.limiter.Wait()
+
+	tracking, err := c.tracker()
+	if err != nil || tracking == nil {
+		return nil, nil, err
+	}
+
+	c.limiter.Wait()
+
+	entry, err := c.timeEntry(tracking.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tracking, entry, nil
+}
+
+// StartTracker starts the tracker on the entry, mite stops a tracker running on another entry and returns it as stopped
+func (c *Client) StartTracker(id domain.TimeEntryId) (*domain.TrackingTimeEntry, *domain.StoppedTimeEntry, error) {
+	c.limiter.Wait()
+
+	res := trackerResponse{}
+
+	err := c.rest.patch(fmt.Sprintf("/tracker/%s.json", id), nil, &res)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res.tracking(), res.stopped(), nil
+}
+
+// StopTracker stops the running tracker, it returns nil if no tracker runs
+func (c *Client) StopTracker() (*domain.StoppedTimeEntry, error) {
+	c.limiter.Wait()
+
+	tracking, err := c.tracker()
+	if err != nil || tracking == nil {
+		return nil, err
+	}
+
+	c.limiter.Wait()
+
+	res := trackerResponse{}
+
+	err = c.rest.delete(fmt.Sprintf("/tracker/%s.json", tracking.Id), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.stopped(), nil
+}
+
+// CreateEntry creates the entry for the current user and returns it as stored by mite, including the names of its
+// project and service
+func (c *Client) CreateEntry(entry *domain.TimeEntry) (*domain.TimeEntry, error) {
+	c.limiter.Wait()
+
+	id, _, err := c.createTimeEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	c.limiter.Wait()
+
+	return c.timeEntry(id)
+}
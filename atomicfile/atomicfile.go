@@ -0,0 +1,19 @@
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// Write replaces the file with the data. The data is written aside and renamed, so a crash never leaves a torn
+// file, readers either see the old or the new content.
+func Write(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+
+	err := ioutil.WriteFile(tmp, data, perm)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
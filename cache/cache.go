@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"mighty/atomicfile"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Cache keeps the time entries pulled from mite on disk, so a pull only fetches the recent entries and the
+// timesheet can be pulled from the cache while mite is unreachable
+type Cache struct {
+	path string
+	// Watermark is the time of the last fetch which reached today, zero for an empty cache
+	Watermark time.Time
+	// From and To are the dates the cache holds every entry between, both inclusive
+	From    domain.LocalDate
+	To      domain.LocalDate
+	entries map[domain.TimeEntryId]*domain.TimeEntry
+}
+
+// cacheFile is the stored form of the cache, the domain types don't carry json tags
+type cacheFile struct {
+	Watermark time.Time `json:"watermark"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Entries   []entry   `json:"entries"`
+}
+
+type entry struct {
+	Id           int       `json:"id"`
+	Date         string    `json:"date"`
+	Minutes      int       `json:"minutes"`
+	Note         string    `json:"note"`
+	Billable     bool      `json:"billable"`
+	Locked       bool      `json:"locked"`
+	ProjectId    int       `json:"projectId"`
+	ProjectName  string    `json:"projectName"`
+	CustomerId   int       `json:"customerId"`
+	CustomerName string    `json:"customerName"`
+	ServiceId    int       `json:"serviceId"`
+	ServiceName  string    `json:"serviceName"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// Path returns the path of the cache of the account, the token tells users of the same account apart without
+// being part of the name
+func Path(baseUrl, token string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum([]byte(baseUrl + "|" + token))
+
+	return filepath.Join(dir, "mighty", fmt.Sprintf("entries-%x.json", sum[:6])), nil
+}
+
+// Open reads the cache at the given path, a missing or unreadable cache is empty
+func Open(path string) *Cache {
+	c := &Cache{path: path, entries: make(map[domain.TimeEntryId]*domain.TimeEntry)}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Ignoring the cache %s: %v", path, err)
+		}
+
+		return c
+	}
+
+	f := cacheFile{}
+	if err = json.Unmarshal(b, &f); err != nil {
+		log.Warnf("Ignoring the cache %s: %v", path, err)
+		return c
+	}
+
+	from, err := domain.ParseLocalDate(f.From)
+	if err != nil {
+		log.Warnf("Ignoring the cache %s: %v", path, err)
+		return c
+	}
+
+	to, err := domain.ParseLocalDate(f.To)
+	if err != nil {
+		log.Warnf("Ignoring the cache %s: %v", path, err)
+		return c
+	}
+
+	for _, e := range f.Entries {
+		date, err := domain.ParseLocalDate(e.Date)
+		if err != nil {
+			log.Warnf("Ignoring the cached entry %d: %v", e.Id, err)
+			continue
+		}
+
+		c.entries[domain.NewTimeEntryId(e.Id)] = &domain.TimeEntry{
+			Id:           domain.NewTimeEntryId(e.Id),
+			Date:         date,
+			Minutes:      domain.NewMinutes(e.Minutes),
+			Note:         e.Note,
+			Billable:     e.Billable,
+			Locked:       e.Locked,
+			ProjectId:    domain.NewProjectId(e.ProjectId),
+			ProjectName:  e.ProjectName,
+			CustomerId:   domain.NewCustomerId(e.CustomerId),
+			CustomerName: e.CustomerName,
+			ServiceId:    domain.NewServiceId(e.ServiceId),
+			ServiceName:  e.ServiceName,
+			UpdatedAt:    e.UpdatedAt,
+		}
+	}
+
+	c.Watermark = f.Watermark
+	c.From = from
+	c.To = to
+
+	return c
+}
+
+// Covers reports whether the cache holds every entry between the dates, both inclusive
+func (c *Cache) Covers(from, to domain.LocalDate) bool {
+	return !c.Watermark.IsZero() && from.String() >= c.From.String() && to.String() <= c.To.String()
+}
+
+// Entries returns the cached entries between the dates, both inclusive, ordered by date
+func (c *Cache) Entries(from, to domain.LocalDate) []*domain.TimeEntry {
+	var entries []*domain.TimeEntry
+
+	for _, e := range c.entries {
+		if inRange(e.Date, from, to) {
+			entries = append(entries, e)
+		}
+	}
+
+	return sorted(entries)
+}
+
+func sorted(entries []*domain.TimeEntry) []*domain.TimeEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Date.String() != entries[j].Date.String() {
+			return entries[i].Date.Before(entries[j].Date)
+		}
+
+		return entries[i].Id < entries[j].Id
+	})
+
+	return entries
+}
+
+// Update replaces the cached entries between the dates by the entries fetched from mite at fetchedAt. The watermark
+// only moves if the fetch reached the day of fetchedAt, a fetch which ended before leaves the later dates as old as
+// they were. A fetch apart from the cached dates replaces them if it reached that day, otherwise they are kept.
+func (c *Cache) Update(from, to domain.LocalDate, fetched []*domain.TimeEntry, fetchedAt time.Time) {
+	if from.String() > to.String() {
+		return
+	}
+
+	for id, e := range c.entries {
+		if inRange(e.Date, from, to) {
+			delete(c.entries, id)
+		}
+	}
+
+	for _, e := range fetched {
+		c.entries[e.Id] = e
+	}
+
+	reachedToday := to.String() >= domain.NewLocalDate(fetchedAt.Local()).String()
+	joined := !c.Watermark.IsZero() && from.String() <= c.To.Add(0, 0, 1).String() && to.String() >= c.From.Add(0, 0, -1).String()
+
+	switch {
+	case joined:
+		if from.String() < c.From.String() {
+			c.From = from
+		}
+
+		if to.String() > c.To.String() {
+			c.To = to
+		}
+
+		if reachedToday {
+			c.Watermark = fetchedAt
+		}
+	case c.Watermark.IsZero() || reachedToday:
+		c.From, c.To, c.Watermark = from, to, fetchedAt
+	}
+}
+
+// Save writes the cache to disk
+func (c *Cache) Save() error {
+	f := cacheFile{Watermark: c.Watermark, From: c.From.String(), To: c.To.String()}
+
+	all := make([]*domain.TimeEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		all = append(all, e)
+	}
+
+	for _, e := range sorted(all) {
+		f.Entries = append(f.Entries, entry{
+			Id:           int(e.Id),
+			Date:         e.Date.String(),
+			Minutes:      e.Minutes.Value(),
+			Note:         e.Note,
+			Billable:     e.Billable,
+			Locked:       e.Locked,
+			ProjectId:    int(e.ProjectId),
+			ProjectName:  e.ProjectName,
+			CustomerId:   int(e.CustomerId),
+			CustomerName: e.CustomerName,
+			ServiceId:    int(e.ServiceId),
+			ServiceName:  e.ServiceName,
+			UpdatedAt:    e.UpdatedAt,
+		})
+	}
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.path), 0700)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(c.path, b, 0600)
+}
+
+// inRange compares by day, the dates may carry a time of day, e.g. domain.Today
+func inRange(date, from, to domain.LocalDate) bool {
+	return date.String() >= from.String() && date.String() <= to.String()
+}
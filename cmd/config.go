@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	str2duration "github.com/xhit/go-str2duration/v2"
+	"mighty/api"
+	"mighty/config"
+	"net/url"
+	"os"
+	"text/tabwriter"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Shows, changes and checks the config",
+		Long: `Shows, changes and checks the config.
+
+$ mighty config path                # prints the config file in use
+$ mighty config view                # prints the effective settings and where they come from
+$ mighty config set history 3months # writes a setting, to the selected profile if there is one
+$ mighty config validate            # checks the settings and the token against mite
+
+The settings of the config file are overridden by the selected profile, by MIGHTY_<SETTING> environment variables
+and by flags like '--history', in this order. The token is always redacted.
+`,
+	}
+
+	configPathCmd = &cobra.Command{
+		Use:   "path",
+		Short: "Prints the config file in use",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			path, err := config.Path()
+			if err != nil {
+				logger.Fatalf("No config found, use 'mighty gen config' to create one: %v", err)
+			}
+
+			fmt.Println(path)
+		},
+	}
+
+	configViewCmd = &cobra.Command{
+		Use:   "view",
+		Short: "Prints the effective settings and where they come from",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			config.ReadCfg()
+
+			err := printSettings(config.Settings())
+			if err != nil {
+				logger.Fatal(err)
+			}
+		},
+	}
+
+	configSetCmd = &cobra.Command{
+		Use:   "set <setting> <value>",
+		Short: "Writes a setting to the config file, to the selected profile if there is one",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if args[0] == "token" {
+				logger.Warn("The token is written in plain text, consider 'token_command' or 'token_file' instead")
+			}
+
+			path, err := config.Set(args[0], args[1])
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Infof("Set %s in %s", args[0], path)
+		},
+	}
+
+	configValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Checks the settings and whether mite accepts the token",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			config.ReadCfg()
+
+			problems := validateConfig(config.CurrentConfig)
+			if problems > 0 {
+				logger.Fatalf("Found %d problems in the config", problems)
+			}
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configPathCmd, configViewCmd, configSetCmd, configValidateCmd)
+}
+
+func printSettings(settings []config.Setting) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	if config.CurrentConfig.Profile != "" {
+		_, _ = fmt.Fprintf(w, "profile\t%s\t\n", config.CurrentConfig.Profile)
+	}
+
+	for _, s := range settings {
+		_, _ = fmt.Fprintf(w, "%s\t%v\t%s\n", s.Key, s.Value, s.Source)
+	}
+
+	return w.Flush()
+}
+
+// validateConfig prints a line per check and returns the number of failed ones, the token is only checked against
+// mite if the url is valid
+func validateConfig(cfg config.MightyConfig) int {
+	problems := 0
+
+	check := func(setting string, err error, ok string) bool {
+		if err != nil {
+			problems++
+			fmt.Printf("problem  %s: %v\n", setting, err)
+			return false
+		}
+
+		fmt.Printf("ok       %s: %s\n", setting, ok)
+		return true
+	}
+
+	validUrl := check("url", validateUrl(cfg.MiteUrl), cfg.MiteUrl)
+
+	from, to, err := api.HistoryRange(cfg.EntriesHistory)
+	check("history", err, fmt.Sprintf("%s to %s", from, to))
+
+	_, err = str2duration.ParseDuration(cfg.CacheWindow)
+	check("cache_window", err, cfg.CacheWindow)
+
+	if cfg.OvertimeStart != "" {
+		_, err = domain.ParseLocalDate(cfg.OvertimeStart)
+		check("overtime_start", err, cfg.OvertimeStart)
+	}
+
+	if !validUrl {
+		return problems
+	}
+
+	account, err := fetchAccount()
+	if err == nil {
+		check("token", nil, fmt.Sprintf("accepted for the account %s (%s)", account.Title, account.Name))
+	} else {
+		check("token", fmt.Errorf("mite doesn't accept it: %v", err), "")
+	}
+
+	return problems
+}
+
+func validateUrl(miteUrl string) error {
+	u, err := url.Parse(miteUrl)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+		return fmt.Errorf("%q isn't an absolute http(s) url like https://yours.mite.yo.lk", miteUrl)
+	}
+
+	return nil
+}
+
+func fetchAccount() (*api.Account, error) {
+	client, err := createClientFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Account()
+}
@@ -18,6 +18,22 @@ Supports generating the template and has the following options (case sensitive):
 * Configuration file:
 $ mighty gen config  # uses the default file path
 $ mighty gen config --configfile /path/to/file
+$ mighty gen config --profile client  # adds the profile client, also to an existing file
+
+Settings of a profile, e.g. url, token, history and timesheet, override the top level ones whenever the profile is
+selected by '--profile' or MIGHTY_PROFILE:
+
+url: https://company.mite.yo.lk
+token: <get_your_token>
+profiles:
+  client:
+    url: https://client.mite.yo.lk
+    token: <get_your_token>
+    timesheet: ~/entries-client.xlsx
+
+Rather than keeping the token in the config, set 'token_command' to a command printing it, e.g. "pass show mite",
+or 'token_file' to a file only you may read (chmod 600). MIGHTY_TOKEN overrides all of them, the other settings
+are read from MIGHTY_<SETTING> as well, e.g. MIGHTY_TOKEN_COMMAND. The token is never logged.
 
 
 * timesheet file:
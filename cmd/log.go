@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	str2duration "github.com/xhit/go-str2duration/v2"
+	"mighty/config"
+	"mighty/export"
+	"mighty/reference"
+	"strings"
+	"time"
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log <duration> [note]",
+	Short: "Books an entry in mite without opening the timesheet",
+	Long: `Books an entry in mite without opening the timesheet.
+
+The project and service are resolved by the Projects and Services sheets of the timesheet, like the rows of the
+month sheets, the entry is billable if its service is. The date is either today, yesterday or formatted as yyyy-mm-dd:
+
+$ mighty log 1h30m -p "ACME / Maintenance" -s Development -d yesterday "Fixed login bug"
+$ mighty log 45m -p Internal -s Meeting
+
+The entry is appended to its month sheet of the timesheet as well, use '--append=false' to only create it in mite.
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		config.ReadCfg()
+
+		minutes, err := parseLogDuration(args[0])
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		projectName, err := cmd.Flags().GetString("project")
+		if err != nil {
+			logger.Fatal("Unable to read the project flag", err)
+		}
+
+		serviceName, err := cmd.Flags().GetString("service")
+		if err != nil {
+			logger.Fatal("Unable to read the service flag", err)
+		}
+
+		if projectName == "" || serviceName == "" {
+			logger.Fatal("Use --project and --service to name the project and service of the entry")
+		}
+
+		dateFlag, err := cmd.Flags().GetString("date")
+		if err != nil {
+			logger.Fatal("Unable to read the date flag", err)
+		}
+
+		date, err := parseDay(dateFlag)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		appendRow, err := cmd.Flags().GetBool("append")
+		if err != nil {
+			logger.Fatal("Unable to read the append flag", err)
+		}
+
+		exportFile, err := openTimesheet(cmd)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		project, service, err := exportFile.ResolveReference(projectName, serviceName)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		client, err := createClientFromConfig()
+		if err != nil {
+			logger.Fatalf("Unable to create api client %v", err)
+		}
+
+		entry, err := client.CreateEntry(&domain.TimeEntry{
+			Date:      date,
+			Minutes:   minutes,
+			Note:      strings.Join(args[1:], " "),
+			Billable:  service.Billable,
+			ProjectId: project.Id,
+			ServiceId: service.Id,
+		})
+		if err != nil {
+			logger.Fatalf("Unable to create the entry %v", err)
+		}
+
+		fmt.Printf("Created [%s] %s | %s | %s | %s | %s\n", entry.Id, entry.Date,
+			reference.DisplayName(entry.CustomerName, entry.ProjectName), entry.ServiceName, entry.Minutes.String(), entry.Note)
+
+		if !appendRow {
+			return
+		}
+
+		err = exportFile.AppendEntry(entry)
+		if err != nil {
+			logger.Fatalf("Unable to add the entry to %s, the next pull adds it: %v", exportFile.FileName(), err)
+		}
+
+		logger.Infof("Added [%s] to '%s' of %s", entry.Id, export.MonthSheetName(entry.Date), exportFile.FileName())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logCmd)
+	logCmd.Flags().StringP("project", "p", "", "project of the entry, e.g. \"Customer / Project\"")
+	logCmd.Flags().StringP("service", "s", "", "service of the entry")
+	logCmd.Flags().StringP("date", "d", "today", "date of the entry, either today, yesterday or formatted as yyyy-mm-dd")
+	logCmd.Flags().Bool("append", true, "appends the entry to its month sheet of the timesheet")
+}
+
+// parseLogDuration parses the time of an entry like 1h30m or 45m, rounded to minutes
+func parseLogDuration(s string) (domain.Minutes, error) {
+	dur, err := str2duration.ParseDuration(s)
+	if err != nil {
+		return domain.Minutes{}, fmt.Errorf("unable to parse duration %q, expected e.g. 1h30m or 45m", s)
+	}
+
+	dur = dur.Round(time.Minute)
+	if dur <= 0 {
+		return domain.Minutes{}, errors.New("the duration has to be at least a minute")
+	}
+
+	return domain.NewMinutes(int(dur.Minutes())), nil
+}
+
+// parseDay parses today, yesterday or a date formatted as yyyy-mm-dd
+func parseDay(s string) (domain.LocalDate, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "today":
+		return domain.Today(), nil
+	case "yesterday":
+		return domain.Today().Add(0, 0, -1), nil
+	default:
+		date, err := domain.ParseLocalDate(s)
+		if err != nil {
+			return domain.LocalDate{}, fmt.Errorf("unable to parse date %q, expected today, yesterday or yyyy-mm-dd", s)
+		}
+
+		return date, nil
+	}
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"mighty/api"
+	"mighty/config"
+	"os"
+)
+
+var (
+	queueCmd = &cobra.Command{
+		Use:   "queue",
+		Short: "Shows and replays the pushes queued while mite was unreachable",
+		Long: `Shows and replays the pushes queued while mite was unreachable.
+
+A sync which can't reach mite queues its operations next to the timesheet and flags their rows as pending.
+The next sync replays the queue first, 'mighty queue flush' replays it without pushing anything else:
+
+$ mighty queue show
+$ mighty queue flush
+
+Queued updates and deletes of entries which were changed in mite in the meantime are skipped, the next sync merges
+them instead. The replay only writes the ids of the created entries into the timesheet, the next sync pulls the rest.
+`,
+	}
+
+	queueShowCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Prints the queued operations",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			config.ReadCfg()
+
+			excelFilePath, err := timesheetPath(cmd)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			plan, err := api.QueuedPlan(excelFilePath)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			if len(plan.Operations) == 0 {
+				fmt.Println("Nothing is queued.")
+				return
+			}
+
+			err = plan.WriteText(os.Stdout)
+			if err != nil {
+				logger.Fatal(err)
+			}
+		},
+	}
+
+	queueFlushCmd = &cobra.Command{
+		Use:   "flush",
+		Short: "Replays the queued operations and writes the ids of the created entries into the timesheet",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			config.ReadCfg()
+
+			excelFilePath, err := timesheetPath(cmd)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			if !api.QueueExists(excelFilePath) {
+				fmt.Println("Nothing is queued.")
+				return
+			}
+
+			client, err = createClientFromConfig()
+			if err != nil {
+				logger.Fatalf("Unable to create api client %v", err)
+			}
+
+			currentConfig = config.CurrentConfig
+
+			_, err = flushQueue(excelFilePath)
+			if err != nil {
+				reportSyncError(err)
+			}
+
+			if api.QueueExists(excelFilePath) {
+				logger.Fatalf("mite is unreachable, the operations stay queued in %s", api.QueuePath(excelFilePath))
+			}
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueShowCmd, queueFlushCmd)
+}
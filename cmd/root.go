@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"fmt"
+	"github.com/mitchellh/go-homedir"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"mighty/config"
+	"mighty/export"
 	"os"
 )
 
@@ -27,7 +30,11 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().String("config", "", "config file (default $HOME/.mighty.yaml)")
-	rootCmd.PersistentFlags().String("timesheet", "", "the file which stores the timesheet entries (default $HOME/entries.xlsx")
+	rootCmd.PersistentFlags().String("timesheet", "", "the file which stores the timesheet entries "+
+		"(default 'timesheet' of the config or $HOME/entries.xlsx)")
+	rootCmd.PersistentFlags().String("profile", "", "the profile of the config to use (default $"+config.ProfileEnv+")")
+	rootCmd.PersistentFlags().String("history", "", "the entries to pull instead of 'history' of the config, e.g. 4w, "+
+		"this-month, last-month, \"3 months\", quarter, ytd or 2026-01-01..2026-03-31")
 	log.SetOutput(os.Stdout)
 	cobra.OnInitialize(initConfig)
 }
@@ -37,5 +44,47 @@ func initConfig() {
 	if err != nil {
 		log.Fatal("Unable to read config file", err)
 	}
+	profile, err := rootCmd.Flags().GetString("profile")
+	if err != nil {
+		log.Fatal("Unable to read the profile flag", err)
+	}
+
+	config.UseProfile(profile)
+	config.BindFlag("history", rootCmd.PersistentFlags().Lookup("history"))
 	config.SetupCfg(cfgFile, false)
 }
+
+// timesheetPath returns the path of the timesheet given by --timesheet, by default the one of the config
+func timesheetPath(cmd *cobra.Command) (string, error) {
+	file, err := cmd.Flags().GetString("timesheet")
+	if err != nil {
+		return "", err
+	}
+
+	if file == "" {
+		file = config.CurrentConfig.Timesheet
+	}
+
+	if file == "" {
+		file = config.DefaultConfig.Timesheet
+	}
+
+	return homedir.Expand(file)
+}
+
+// openTimesheet reads the timesheet given by --timesheet, e.g. to resolve projects and services by its reference sheets
+func openTimesheet(cmd *cobra.Command) (*export.XlFile, error) {
+	file, err := timesheetPath(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	exportFile := export.ExcelFile(file)
+
+	err = exportFile.ReloadFromDisk()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s, pull it with `mighty sync --onlyPull` first: %v", file, err)
+	}
+
+	return exportFile, nil
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"mighty/api"
+	"mighty/config"
+	"mighty/reference"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Shows the booked hours of today, this week and this month compared to the targets",
+	Long: `Shows the booked hours of today, this week and this month compared to the targets, broken down by project
+and billable vs. non-billable, along with the overtime balance.
+
+The targets are configured by 'daily_target' and 'weekly_target', e.g. 8h and 40h. The overtime balance compares the
+booked hours with the daily target of every working day from Monday to Friday, including today. It starts at
+'overtime_start', formatted as yyyy-mm-dd, or at the beginning of the configured 'history'.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		config.ReadCfg()
+
+		client, err := createClientFromConfig()
+		if err != nil {
+			logger.Fatalf("Unable to create api client %v", err)
+		}
+
+		err = printStatus(client, config.CurrentConfig)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
+
+// bookedHours are the minutes booked in a period
+type bookedHours struct {
+	name      string
+	from      domain.LocalDate
+	to        domain.LocalDate
+	target    int
+	total     int
+	billable  int
+	byProject map[string]int
+}
+
+func (b *bookedHours) add(entry *domain.TimeEntry) {
+	if !inRange(entry.Date, b.from, b.to) {
+		return
+	}
+
+	minutes := entry.Minutes.Value()
+
+	b.total += minutes
+	if entry.Billable {
+		b.billable += minutes
+	}
+
+	b.byProject[reference.DisplayName(entry.CustomerName, entry.ProjectName)] += minutes
+}
+
+func printStatus(client *api.Client, cfg config.MightyConfig) error {
+	today, err := domain.ParseLocalDate(domain.Today().String())
+	if err != nil {
+		return err
+	}
+
+	daily := int(cfg.DailyTarget.Minutes())
+	weekStart := today.Add(0, 0, -(int(weekday(today))+6)%7)
+	monthStart := firstOfMonth(today)
+
+	overtimeStart, err := overtimeStartDate(cfg)
+	if err != nil {
+		return err
+	}
+
+	periods := []*bookedHours{
+		{name: "Today", from: today, to: today, target: daily * workingDays(today, today)},
+		{name: "This week", from: weekStart, to: weekStart.Add(0, 0, 6), target: int(cfg.WeeklyTarget.Minutes())},
+		{name: "This month", from: monthStart, to: lastOfMonth(today), target: daily * workingDays(monthStart, lastOfMonth(today))},
+	}
+
+	overtime := &bookedHours{name: "Overtime", from: overtimeStart, to: today, target: daily * workingDays(overtimeStart, today)}
+
+	from := overtimeStart
+	for _, p := range periods {
+		p.byProject = make(map[string]int)
+		if p.from.Before(from) {
+			from = p.from
+		}
+	}
+	overtime.byProject = make(map[string]int)
+
+	entries, err := client.FetchEntriesBetween(from, today)
+	if err != nil {
+		return err
+	}
+
+	all := append(periods, overtime)
+	for _, entry := range entries {
+		for _, p := range all {
+			p.add(entry)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "\tBooked\tTarget\tBillable\tNon-billable")
+
+	for _, p := range periods {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.name, hours(p.total), hours(p.target), hours(p.billable),
+			hours(p.total-p.billable))
+
+		for _, project := range sortedProjects(p.byProject) {
+			_, _ = fmt.Fprintf(w, "  %s\t%s\t\t\t\n", project, hours(p.byProject[project]))
+		}
+	}
+
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintf(w, "Overtime since %s\t%s\t\t\t\n", overtimeStart, signedHours(overtime.total-overtime.target))
+
+	return w.Flush()
+}
+
+// overtimeStartDate returns the configured start of the overtime balance, by default the start of the history
+func overtimeStartDate(cfg config.MightyConfig) (domain.LocalDate, error) {
+	if cfg.OvertimeStart != "" {
+		date, err := domain.ParseLocalDate(cfg.OvertimeStart)
+		if err != nil {
+			return domain.LocalDate{}, fmt.Errorf("unable to parse overtime_start %q, expected yyyy-mm-dd", cfg.OvertimeStart)
+		}
+
+		return date, nil
+	}
+
+	from, _, err := api.HistoryRange(cfg.EntriesHistory)
+	if err != nil {
+		return domain.LocalDate{}, err
+	}
+
+	return domain.ParseLocalDate(from.String())
+}
+
+// workingDays counts the days from Monday to Friday between the dates, both inclusive
+func workingDays(from, to domain.LocalDate) int {
+	days := 0
+
+	for d := from; inRange(d, from, to); d = d.Add(0, 0, 1) {
+		if wd := weekday(d); wd != time.Saturday && wd != time.Sunday {
+			days++
+		}
+	}
+
+	return days
+}
+
+func weekday(date domain.LocalDate) time.Weekday {
+	return time.Unix(date.Unix(), 0).Weekday()
+}
+
+// sortedProjects returns the projects with the most hours first
+func sortedProjects(byProject map[string]int) []string {
+	projects := make([]string, 0, len(byProject))
+	for project := range byProject {
+		projects = append(projects, project)
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		if byProject[projects[i]] != byProject[projects[j]] {
+			return byProject[projects[i]] > byProject[projects[j]]
+		}
+
+		return strings.ToLower(projects[i]) < strings.ToLower(projects[j])
+	})
+
+	return projects
+}
+
+// hours formats minutes as h:mm
+func hours(minutes int) string {
+	if minutes < 0 {
+		return "-" + hours(-minutes)
+	}
+
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
+
+func signedHours(minutes int) string {
+	if minutes > 0 {
+		return "+" + hours(minutes)
+	}
+
+	return hours(minutes)
+}
@@ -1,15 +1,27 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
 	"github.com/leanovate/mite-go/domain"
 	"github.com/mitchellh/go-homedir"
 	logger "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	str2duration "github.com/xhit/go-str2duration/v2"
 	"mighty/api"
+	"mighty/cache"
 	"mighty/config"
 	"mighty/export"
+	"mighty/merge"
+	"os"
+	"strings"
+	"time"
 )
 
+// errQueued reports a push which was queued as mite is unreachable
+var errQueued = errors.New("mite is unreachable")
+
 // syncCmd represents the sync command
 var (
 	client        *api.Client
@@ -20,27 +32,151 @@ var (
 		Short: "Syncs the timesheet entries from an excel sheet file defined by --timesheet parameter",
 		Long: `Syncs the timesheet entries from an excel sheet file defined by --timesheet parameter
 
-Note that the syncing only works for current month. Use '--onlyPull' to fetch the past entries for the correct format.
+By default only the current month is pushed, use '--month' or '--from' and '--to' to push other months of the timesheet.
+Use '--onlyPull' to fetch the past entries for the correct format.
 A typical workflow can be: 
 
 $ mighty sync --onlyPull mite-entries.xlsx
 $ // update the entries 
 $ mighty sync mite-entries.xlsx
 
+The pull fetches the entries of the 'history' in the config, or of '--history'. Besides a duration like 4w, the
+history is a calendar period which keeps whole months in the timesheet: this-month, last-month, "3 months" (the
+current and the two previous months), quarter, ytd or explicit dates like 2026-01-01..2026-03-31.
+
+The pull updates the timesheet in place: rows of entries are updated, new entries are appended and rows of entries
+deleted in mite are removed. Additional sheets, rows without entry id and columns right of the entries are kept.
+
+Every sync remembers the last synced state of each entry. Entries changed only in the timesheet are pushed,
+entries changed only in mite are pulled and entries changed on both sides are flagged as conflicts in the timesheet.
+Conflicts can be resolved by fixing the flagged rows or with '--prefer local' or '--prefer remote'.
+
+Use '--dry-run' to print the planned creates, updates and deletes without changing anything in mite or the timesheet:
+
+$ mighty sync --dry-run
+$ mighty sync --dry-run --plan-format json > plan.json
+
+To push corrections of past months:
+
+$ mighty sync --month "September 2026"
+$ mighty sync --from 2026-08-01 --to 2026-09-30
+
+Before anything is pushed all rows are validated. Problems like unknown projects or services, unparsable dates
+or times are reported together and nothing is pushed until they are fixed, or '--skip-invalid' is given to
+push the valid rows only. A row copied along with its entry id is a problem, new rows with the same date, project,
+service, time and note as another row or as an entry created in mite since the last pull are reported as likely
+duplicates, use '--allow-duplicates' if they are intended. Projects are named "Customer / Project" as listed in
+the Projects sheet, the project name alone is accepted as long as no other customer has a project of that name.
+Archived projects and services are hidden in their sheets and only accepted for entries which already exist in
+mite. An empty 'Billable?' cell takes the billable default of the service, as listed in the Services sheet.
+
+Every push is recorded in a journal next to the timesheet. If a push is interrupted, e.g. by a network drop,
+resume it with '--resume', which skips everything that already reached mite and never creates entries twice.
+
+To delete an entry, set its time to 00:00, remove its row or add an 'Action' column right of the entries and
+set it to 'delete'. Removed rows are only deleted in mite after a confirmation or with '--allow-deletes'. Set the
+action to 'ignore' to leave a row out of the push, 'keep' or an empty action pushes it as usual.
+
+Use '--watch' to keep mighty running after the sync. Whenever the timesheet is saved the changed months are pushed,
+and pulled back only if something was pushed, so the open workbook is rewritten as rarely as possible:
+
+$ mighty sync --watch
+
+The entries pulled from mite are cached, later pulls only fetch the entries of the 'cache_window' (default 2w)
+before the last pull and take the older ones from the cache. mite can't be asked for the entries changed since a
+date, so older entries changed or deleted directly in mite keep their cached values until a pull with '--full'.
+While mite is unreachable '--onlyPull' updates the timesheet from the cache.
+
+If mite is unreachable, e.g. without network, the push is queued next to the timesheet and its rows are flagged as
+pending. The next sync or 'mighty queue flush' replays the queue once mite is reachable again.
+
+Entries locked in mite, e.g. because they were invoiced, are greyed out and protected in the timesheet.
+Changes of locked entries are reported and skipped, the next pull restores their values from mite.
 `,
 		Run: func(cmd *cobra.Command, _ []string) {
 
 			config.ReadCfg()
 
-			file, err := cmd.Flags().GetString("timesheet")
+			file, err := timesheetPath(cmd)
 			if err != nil {
 				logger.Fatal("Unable to read the file flag", err)
 			}
 
-			onlyPull, err := cmd.Flags().GetBool("onlyPull")
+			opts := syncOptions{}
+
+			opts.onlyPull, err = cmd.Flags().GetBool("onlyPull")
 			if err != nil {
 				return
 			}
+
+			preferFlag, err := cmd.Flags().GetString("prefer")
+			if err != nil {
+				logger.Fatal("Unable to read the prefer flag", err)
+			}
+
+			opts.prefer, err = merge.ParsePreference(preferFlag)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			opts.dryRun, err = cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				logger.Fatal("Unable to read the dry-run flag", err)
+			}
+
+			opts.planFormat, err = cmd.Flags().GetString("plan-format")
+			if err != nil {
+				logger.Fatal("Unable to read the plan-format flag", err)
+			}
+
+			opts.resume, err = cmd.Flags().GetBool("resume")
+			if err != nil {
+				logger.Fatal("Unable to read the resume flag", err)
+			}
+
+			opts.skipInvalid, err = cmd.Flags().GetBool("skip-invalid")
+			if err != nil {
+				logger.Fatal("Unable to read the skip-invalid flag", err)
+			}
+
+			opts.allowDeletes, err = cmd.Flags().GetBool("allow-deletes")
+			if err != nil {
+				logger.Fatal("Unable to read the allow-deletes flag", err)
+			}
+
+			opts.allowDuplicates, err = cmd.Flags().GetBool("allow-duplicates")
+			if err != nil {
+				logger.Fatal("Unable to read the allow-duplicates flag", err)
+			}
+
+			opts.full, err = cmd.Flags().GetBool("full")
+			if err != nil {
+				logger.Fatal("Unable to read the full flag", err)
+			}
+
+			opts.watch, err = cmd.Flags().GetBool("watch")
+			if err != nil {
+				logger.Fatal("Unable to read the watch flag", err)
+			}
+
+			if opts.watch && opts.onlyPull {
+				logger.Fatal("--watch pushes the changes of the timesheet, it can't be combined with --onlyPull")
+			}
+
+			opts.from, opts.to, err = readPushRange(cmd)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			switch opts.planFormat {
+			case "text":
+			case "json":
+				// keep stdout clean for the plan
+				logger.SetOutput(os.Stderr)
+			default:
+				logger.Fatalf("Unsupported plan format %s, use either `text` or `json`", opts.planFormat)
+			}
+
 			client, err = createClientFromConfig()
 			if err != nil {
 				logger.Fatalf("Unable to create api client %v", err)
@@ -48,9 +184,16 @@ $ mighty sync mite-entries.xlsx
 
 			currentConfig = config.CurrentConfig
 
-			err = syncFile(file, onlyPull)
+			err = syncFile(file, opts)
 			if err != nil {
-				logger.Fatalf("Unable to sync entries to file %v", err)
+				reportSyncError(err)
+			}
+
+			if opts.watch {
+				err = watchFile(file, opts)
+				if err != nil {
+					logger.Fatalf("Unable to watch %s %v", file, err)
+				}
 			}
 		},
 	}
@@ -59,76 +202,714 @@ $ mighty sync mite-entries.xlsx
 func init() {
 	rootCmd.AddCommand(syncCmd)
 	syncCmd.Flags().Bool("onlyPull", false, "only pulls the data from mite, updated entries will be overwritten")
+	syncCmd.Flags().String("prefer", "", "resolves conflicting entries by preferring either the `local` or the `remote` changes")
+	syncCmd.Flags().Bool("dry-run", false, "only prints the plan of the push, neither mite nor the timesheet are changed")
+	syncCmd.Flags().String("plan-format", "text", "format of the printed plan, either `text` or `json`")
+	syncCmd.Flags().String("from", "", "pushes the entries from this date on, formatted as yyyy-mm-dd (default first day of the current month)")
+	syncCmd.Flags().String("to", "", "pushes the entries up to this date, formatted as yyyy-mm-dd (default last day of the month of --from)")
+	syncCmd.Flags().String("month", "", "pushes the entries of a single month sheet, e.g. \"September 2026\"")
+	syncCmd.Flags().Bool("resume", false, "resumes an interrupted push using its journal")
+	syncCmd.Flags().Bool("skip-invalid", false, "pushes the valid rows even if other rows have problems")
+	syncCmd.Flags().Bool("allow-deletes", false, "deletes the entries whose rows were removed from the timesheet without asking")
+	syncCmd.Flags().Bool("full", false, "fetches the whole history from mite instead of the 'cache_window', "+
+		"which misses the changes made in mite to older entries")
+	syncCmd.Flags().Bool("watch", false, "keeps running and syncs the changed months whenever the timesheet is saved")
+	syncCmd.Flags().Bool("allow-duplicates", false, "pushes new rows which look like duplicates of other rows or entries, only warning about them")
+}
+
+type syncOptions struct {
+	onlyPull        bool
+	prefer          merge.Preference
+	dryRun          bool
+	planFormat      string
+	from            domain.LocalDate
+	to              domain.LocalDate
+	resume          bool
+	skipInvalid     bool
+	allowDeletes    bool
+	allowDuplicates bool
+	watch           bool
+	full            bool
+}
+
+// readPushRange returns the dates to push from the --month, --from and --to flags
+func readPushRange(cmd *cobra.Command) (domain.LocalDate, domain.LocalDate, error) {
+	month, err := cmd.Flags().GetString("month")
+	if err != nil {
+		return domain.LocalDate{}, domain.LocalDate{}, err
+	}
+
+	fromFlag, err := cmd.Flags().GetString("from")
+	if err != nil {
+		return domain.LocalDate{}, domain.LocalDate{}, err
+	}
+
+	toFlag, err := cmd.Flags().GetString("to")
+	if err != nil {
+		return domain.LocalDate{}, domain.LocalDate{}, err
+	}
+
+	if month != "" {
+		if fromFlag != "" || toFlag != "" {
+			return domain.LocalDate{}, domain.LocalDate{}, errors.New("--month can't be combined with --from or --to")
+		}
+
+		first, err := export.ParseMonthSheetName(month)
+		if err != nil {
+			return domain.LocalDate{}, domain.LocalDate{}, fmt.Errorf("unable to parse month %q, expected e.g. \"September 2026\"", month)
+		}
+
+		return first, lastOfMonth(first), nil
+	}
+
+	// today carries the time of day, the range starts at midnight
+	today, err := domain.ParseLocalDate(domain.Today().String())
+	if err != nil {
+		return domain.LocalDate{}, domain.LocalDate{}, err
+	}
+
+	from := firstOfMonth(today)
+
+	if fromFlag != "" {
+		from, err = domain.ParseLocalDate(fromFlag)
+		if err != nil {
+			return domain.LocalDate{}, domain.LocalDate{}, fmt.Errorf("unable to parse --from %v", err)
+		}
+	}
+
+	to := lastOfMonth(from)
+
+	if toFlag != "" {
+		to, err = domain.ParseLocalDate(toFlag)
+		if err != nil {
+			return domain.LocalDate{}, domain.LocalDate{}, fmt.Errorf("unable to parse --to %v", err)
+		}
+
+		if fromFlag == "" {
+			from = firstOfMonth(to)
+		}
+	}
+
+	if to.Before(from) {
+		return domain.LocalDate{}, domain.LocalDate{}, fmt.Errorf("--to %s is before --from %s", to, from)
+	}
+
+	return from, to, nil
+}
+
+func firstOfMonth(date domain.LocalDate) domain.LocalDate {
+	return date.Add(0, 0, 1-date.Day())
+}
+
+func lastOfMonth(date domain.LocalDate) domain.LocalDate {
+	return firstOfMonth(date).Add(0, 1, -1)
+}
+
+// inRange compares by day, the dates may carry a time of day, e.g. domain.Today
+func inRange(date, from, to domain.LocalDate) bool {
+	return date.String() >= from.String() && date.String() <= to.String()
 }
 
 func createClientFromConfig() (*api.Client, error) {
-	client, err := api.New(config.CurrentConfig.MiteUrl, config.CurrentConfig.Token)
+	client, err := api.New(config.CurrentConfig.MiteUrl, config.CurrentConfig.Token, api.Options{
+		Concurrency:  config.CurrentConfig.Concurrency,
+		RateLimit:    config.CurrentConfig.RateLimit,
+		Retries:      config.CurrentConfig.Retries,
+		RetryMaxWait: config.CurrentConfig.RetryMaxWait,
+	})
 	if err != nil {
 		return nil, err
 	}
 	return client, nil
 }
 
-func syncFile(excelFile string, onlyPull bool) error {
+func syncFile(excelFile string, opts syncOptions) error {
 	excelFilePath, err := homedir.Expand(excelFile)
 	if err != nil {
 		return err
 	}
 
-	if !onlyPull {
-		err = pushToFile(excelFilePath, domain.Today())
+	var conflicts []merge.Conflict
+
+	refetchFrom := domain.Today()
+	if opts.full {
+		refetchFrom = domain.LocalDate{}
+	}
+
+	if !opts.onlyPull && !opts.dryRun && api.QueueExists(excelFilePath) {
+		resume, err := flushQueue(excelFilePath)
+		if err != nil {
+			return err
+		}
+
+		opts.resume = opts.resume || resume
+	}
+
+	if !opts.onlyPull {
+		var pushed int
+
+		conflicts, pushed, err = pushToFile(excelFilePath, opts)
+		if errors.Is(err, errQueued) {
+			logger.Warn(err)
+			return nil
+		}
 		if err != nil {
 			return err
 		}
+
+		// the cache doesn't know the pushed changes yet
+		if pushed > 0 && opts.from.String() < refetchFrom.String() {
+			refetchFrom = opts.from
+		}
 	}
 
-	err = pullToFile(excelFilePath)
+	if opts.dryRun {
+		return nil
+	}
+
+	err = pullToFile(excelFilePath, conflicts, refetchFrom)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// the pushed entries and their ids are in the workbook now
+	return api.RemoveJournal(excelFilePath)
 }
 
-func pushToFile(excelFilePath string, date domain.LocalDate) error {
-	exportFile := currentConfig.CurrentExportFile
+// queuePlan stores the plan of a push while mite is unreachable and flags its rows in the workbook as pending
+func queuePlan(exportFile *export.XlFile, excelFilePath string, plan *api.Plan, rows []export.Row, opts syncOptions) error {
+	// even an empty plan supersedes the queued operations of its dates
+	err := api.QueuePlan(excelFilePath, plan, opts.from, opts.to)
+	if err != nil {
+		return err
+	}
 
-	if exportFile == nil {
-		exportFile = export.ExcelFile(excelFilePath)
+	if len(plan.Operations) == 0 {
+		return fmt.Errorf("%w, there is nothing to push", errQueued)
 	}
 
-	err := exportFile.ReloadFromDisk()
+	queued := make(map[domain.TimeEntryId]bool, len(plan.Operations))
+	for _, op := range plan.Operations {
+		queued[op.EntryId] = true
+	}
+
+	var pending []export.Row
+
+	for _, row := range rows {
+		// new rows are created, rows with id only if their change is queued
+		if row.Entry.Id == 0 || queued[row.Entry.Id] {
+			pending = append(pending, row)
+		}
+	}
+
+	err = exportFile.MarkPending(pending)
 	if err != nil {
 		return err
 	}
 
-	entries := exportFile.ReadAllEntries(date)
-	err = client.SendEntriesToMite(entries)
+	return fmt.Errorf("%w, %d operations are queued in %s, they are pushed by the next sync or `mighty queue flush`",
+		errQueued, len(plan.Operations), api.QueuePath(excelFilePath))
+}
+
+// flushQueue replays the operations queued while mite was unreachable and writes the ids of the created entries
+// into their rows, so they aren't pushed again. Everything else is left to the following push and pull, the rows
+// may hold changes made since. If mite still is unreachable the push continues the journal of the replay, it
+// reports whether it has to.
+func flushQueue(excelFilePath string) (bool, error) {
+	exportFile := timesheetFile(excelFilePath)
+
+	err := exportFile.ReloadFromDisk()
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	base, err := exportFile.ReadSnapshots()
+	if err != nil {
+		return false, err
+	}
+
+	known := make(map[domain.TimeEntryId]bool, len(base))
+	for id := range base {
+		known[id] = true
+	}
+
+	queued, err := api.ReadQueue(excelFilePath)
+	if err != nil {
+		return false, err
+	}
+
+	created, err := client.FlushQueue(excelFilePath, known)
+	if api.IsUnreachable(err) {
+		logger.Warnf("mite is still unreachable: %v", err)
+		return api.JournalExists(excelFilePath), nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// e.g. saved during the replay
+	err = exportFile.ReloadFromDisk()
+	if err != nil {
+		return false, err
+	}
+
+	missing, err := exportFile.AdoptCreated(created)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range missing {
+		logger.Warnf("[%s] %s %s was created by the replay, but its row was changed since, the next pull adds it as row",
+			entry.Id, entry.Date, entry.ProjectName)
+	}
+
+	err = api.RemoveJournal(excelFilePath)
+	if err != nil {
+		return false, err
+	}
+
+	return false, refreshCache(queued)
 }
 
-func pullToFile(excelFilePath string) error {
-	exportFile := currentConfig.CurrentExportFile
+// refreshCache fetches the entries from the first date of the replayed operations on, the cache may only refetch
+// the recent ones with the next pull
+func refreshCache(replayed []api.QueuedOperation) error {
+	if len(replayed) == 0 {
+		return nil
+	}
 
-	if exportFile == nil {
-		exportFile = export.ExcelFile(excelFilePath)
+	from, to := domain.Today(), domain.Today()
+
+	for _, op := range replayed {
+		date, err := domain.ParseLocalDate(op.Date)
+		if err != nil {
+			return err
+		}
+
+		if date.String() < from.String() {
+			from = date
+		}
+
+		if date.String() > to.String() {
+			to = date
+		}
 	}
 
-	allHistoricEntries, err := client.FetchEntries(currentConfig.EntriesHistory)
-	err = exportFile.SaveAllEntries(allHistoricEntries)
+	_, err := fetchEntries(from, to, from)
+
+	return err
+}
+
+// pushToFile merges the entries of the month sheets between opts.from and opts.to with mite and pushes the
+// local changes, the returned conflicts are not pushed and have to be kept in the file by the following pull.
+// It returns the number of pushed operations as well.
+func pushToFile(excelFilePath string, opts syncOptions) ([]merge.Conflict, int, error) {
+	exportFile := timesheetFile(excelFilePath)
+
+	if api.JournalExists(excelFilePath) && !opts.resume {
+		if !opts.dryRun {
+			return nil, 0, fmt.Errorf("a previous push was interrupted, see %s. Use --resume to continue it", api.JournalPath(excelFilePath))
+		}
+
+		logger.Warnf("A previous push was interrupted, the plan doesn't consider %s", api.JournalPath(excelFilePath))
+	}
+
+	err := exportFile.ReloadFromDisk()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	base, err := exportFile.ReadSnapshots()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var rows []export.Row
+
+	var problems []export.Problem
+
+	for _, month := range exportFile.MonthSheets() {
+		if !inRange(month, firstOfMonth(opts.from), opts.to) {
+			continue
+		}
+
+		monthRows, monthProblems, err := exportFile.ReadAllEntries(month)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		problems = append(problems, monthProblems...)
+
+		for _, row := range monthRows {
+			if inRange(row.Entry.Date, opts.from, opts.to) {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	remote, err := client.FetchEntriesBetween(firstOfMonth(opts.from), lastOfMonth(opts.to))
+
+	offline := err != nil && api.IsUnreachable(err) && !opts.dryRun
+	if err != nil && !offline {
+		return nil, 0, err
+	}
+
+	if offline {
+		logger.Warnf("mite is unreachable, the changes are queued: %v", err)
+
+		// merge against the entries as they were in mite at the last sync
+		remote = nil
+		for _, s := range base {
+			remote = append(remote, s.Entry())
+		}
+	}
+
+	rules := export.Rules{RequireNote: currentConfig.RequireNote, AllowDuplicates: opts.allowDuplicates}
+
+	problems = append(problems, export.ValidateRows(rows, rules)...)
+
+	idProblems, err := exportFile.ValidateIds(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	problems = append(problems, idProblems...)
+
+	// the entries created by the interrupted push are matched by the journal instead
+	if !opts.resume {
+		unknown := unknownEntries(remote, base, export.Entries(rows))
+		problems = append(problems, export.ValidateAgainstRemote(rows, unknown, rules)...)
+	}
+
+	if len(problems) > 0 {
+		reportProblems(problems)
+
+		if !opts.skipInvalid {
+			return nil, 0, fmt.Errorf("the timesheet has %d problems, fix them or use --skip-invalid to push the valid rows only", len(problems))
+		}
+
+		rows = export.WithoutInvalid(rows, problems)
+	}
+
+	local := export.Entries(rows)
+
+	logger.Infof("Read %d entries from %s to %s", len(local), opts.from, opts.to)
+
+	if offline {
+		logger.Warn("Removed rows are only deleted in mite by a sync while mite is reachable")
+	} else {
+		deletions, err := removedRowDeletions(exportFile, base, remote, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		local = append(local, deletions...)
+	}
+
+	result := merge.ThreeWay(local, base, remote, opts.prefer)
+
+	plan, deleted, err := client.PlanEntries(result.Push, remote, offline)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// entries missing in the fetched months, which mite doesn't know anymore
+	for _, entry := range deleted {
+		result.Conflicts = append(result.Conflicts, merge.Conflict{Local: entry, Base: base[entry.Id]})
+	}
+
+	for _, c := range result.Conflicts {
+		logger.Warnf("Conflict %s", c)
+	}
+
+	if len(result.Conflicts) > 0 {
+		logger.Warnf("%d conflicting entries were not pushed and are flagged in %s", len(result.Conflicts), excelFilePath)
+	}
+
+	for _, entry := range result.Locked {
+		logger.Warnf("Locked [%s] %s %s | %s | %s was changed locally but is locked in mite", entry.Id, entry.Date,
+			entry.ProjectName, entry.ServiceName, entry.Minutes.String())
+	}
+
+	if len(result.Locked) > 0 {
+		logger.Warnf("%d locked entries were not pushed, the pull restores their values from mite", len(result.Locked))
+	}
+
+	if opts.dryRun {
+		return result.Conflicts, 0, printPlan(plan, opts.planFormat)
+	}
+
+	if offline {
+		return nil, 0, queuePlan(exportFile, excelFilePath, plan, rows, opts)
+	}
+
+	journal, err := api.OpenJournal(excelFilePath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer func() { _ = journal.Close() }()
+
+	if opts.resume {
+		err = journal.Reconcile(plan, unknownEntries(remote, base, local))
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	err = client.SendEntriesToMite(plan, journal)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%v, use --resume to continue the push", err)
+	}
+
+	return result.Conflicts, len(plan.Operations), nil
+}
+
+// reportSyncError exits with the error, pointing at the broken part of the timesheet if there is one
+func reportSyncError(err error) {
+	var cellErr *export.CellError
+	if errors.As(err, &cellErr) {
+		logger.Fatalf("Unable to sync, cell %s of sheet '%s' is broken: %v. Fix it or pull a fresh timesheet with --onlyPull",
+			cellErr.Axis, cellErr.Sheet, cellErr.Err)
+	}
+
+	var sheetErr *export.SheetError
+	if errors.As(err, &sheetErr) {
+		logger.Fatalf("Unable to sync, sheet '%s' is broken: %v. Fix it or pull a fresh timesheet with --onlyPull", sheetErr.Sheet, sheetErr.Err)
+	}
+
+	logger.Fatalf("Unable to sync entries to file %v", err)
+}
+
+func reportProblems(problems []export.Problem) {
+	out := logger.StandardLogger().Out
+
+	_, _ = fmt.Fprintf(out, "Found %d problems in the timesheet:\n", len(problems))
+	for _, p := range problems {
+		_, _ = fmt.Fprintf(out, "  %s\n", p)
+	}
+}
+
+// removedRowDeletions returns the deletions of the entries whose rows were removed from the timesheet. They are only
+// deleted with --allow-deletes or after a confirmation, otherwise the pull brings their rows back.
+func removedRowDeletions(exportFile *export.XlFile, base map[domain.TimeEntryId]merge.Snapshot, remote []*domain.TimeEntry,
+	opts syncOptions) ([]domain.TimeEntry, error) {
+	removed, err := exportFile.RemovedEntries(base)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteById := make(map[domain.TimeEntryId]*domain.TimeEntry, len(remote))
+	for _, r := range remote {
+		remoteById[r.Id] = r
+	}
+
+	var deletions []domain.TimeEntry
+
+	for _, s := range removed {
+		r, ok := remoteById[s.Id]
+		if !ok || !inRange(s.Date, opts.from, opts.to) {
+			// gone in mite as well or not pushed this time
+			continue
+		}
+
+		if !r.UpdatedAt.Equal(s.UpdatedAt) {
+			logger.Warnf("[%s] %s %s was removed from the timesheet but changed in mite since the last sync, keeping it",
+				r.Id, r.Date, r.ProjectName)
+			continue
+		}
+
+		deletion := *r
+		deletion.Minutes = domain.NewMinutes(0)
+		deletions = append(deletions, deletion)
+	}
+
+	if len(deletions) == 0 {
+		return nil, nil
+	}
+
+	out := logger.StandardLogger().Out
+
+	_, _ = fmt.Fprintf(out, "Found %d entries whose rows were removed from the timesheet:\n", len(deletions))
+	for _, d := range deletions {
+		_, _ = fmt.Fprintf(out, "  [%s] %s %s | %s | %s | %s\n", d.Id, d.Date, d.ProjectName, d.ServiceName, d.Minutes.String(), d.Note)
+	}
+
+	switch {
+	case opts.allowDeletes:
+		return deletions, nil
+	case opts.dryRun:
+		logger.Info("They are deleted after a confirmation or with --allow-deletes")
+		return deletions, nil
+	case !interactive():
+		logger.Warn("Not deleting them without a confirmation, use --allow-deletes to delete them. The pull brings their rows back")
+		return nil, nil
+	case confirm("Delete them in mite?"):
+		return deletions, nil
+	default:
+		logger.Info("Not deleting them, the pull brings their rows back")
+		return nil, nil
+	}
+}
+
+// interactive reports whether the input is a terminal, which can answer confirmations
+func interactive() bool {
+	info, err := os.Stdin.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks the question on the terminal, anything but yes counts as no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// unknownEntries returns the remote entries which neither were pulled nor are in the workbook,
+// i.e. the entries which were created in mite since the last pull
+func unknownEntries(remote []*domain.TimeEntry, base map[domain.TimeEntryId]merge.Snapshot, local []domain.TimeEntry) []*domain.TimeEntry {
+	known := make(map[domain.TimeEntryId]bool, len(local))
+	for _, entry := range local {
+		known[entry.Id] = true
+	}
+
+	var unknown []*domain.TimeEntry
+
+	for _, entry := range remote {
+		if _, ok := base[entry.Id]; !ok && !known[entry.Id] {
+			unknown = append(unknown, entry)
+		}
+	}
+
+	return unknown
+}
+
+func printPlan(plan *api.Plan, format string) error {
+	if format == "json" {
+		return plan.WriteJSON(os.Stdout)
+	}
+
+	if len(plan.Operations) == 0 {
+		fmt.Println("No changes. mite is up-to-date with the timesheet.")
+		return nil
+	}
+
+	return plan.WriteText(os.Stdout)
+}
+
+// pullToFile writes the entries of the history to the timesheet, see fetchEntries for refetchFrom
+func pullToFile(excelFilePath string, conflicts []merge.Conflict, refetchFrom domain.LocalDate) error {
+	from, to, err := api.HistoryRange(currentConfig.EntriesHistory)
+	if err != nil {
+		return err
+	}
+
+	exportFile := timesheetFile(excelFilePath)
+
+	err = pullRange(exportFile, from, to, conflicts, refetchFrom)
 	if err != nil {
 		return err
 	}
 
-	pMap, sMap, err := client.FetchServiceProjects()
+	services, projects, err := client.FetchServiceProjects()
+	if api.IsUnreachable(err) {
+		logger.Warnf("mite is unreachable, keeping the services and projects of %s: %v", excelFilePath, err)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	exportFile.SaveServiceProjects(pMap, sMap)
-	return nil
+	return exportFile.SaveServiceProjects(services, projects)
+}
+
+// pullRange updates the entries between the dates in the timesheet, the entries outside are kept as they are
+func pullRange(exportFile *export.XlFile, from, to domain.LocalDate, conflicts []merge.Conflict, refetchFrom domain.LocalDate) error {
+	entries, err := fetchEntries(from, to, refetchFrom)
+	if err != nil {
+		return err
+	}
+
+	return exportFile.SaveAllEntries(from, to, entries, conflicts)
+}
+
+// fetchEntries returns the entries between the dates. Only the entries since the cache window before the last fetch
+// or since refetchFrom, whichever is earlier, are fetched, the older ones are taken from the cache as long as it holds
+// all of them. The zero date refetches all entries. While mite is unreachable all entries are taken from the cache, as
+// long as it covers the dates.
+func fetchEntries(from, to, refetchFrom domain.LocalDate) ([]*domain.TimeEntry, error) {
+	path, err := cache.Path(currentConfig.MiteUrl, currentConfig.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	c := cache.Open(path)
+
+	fetchFrom := from
+	if !c.Watermark.IsZero() {
+		window, err := str2duration.ParseDuration(currentConfig.CacheWindow)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse cache_window %q: %v", currentConfig.CacheWindow, err)
+		}
+
+		windowFrom := domain.NewLocalDate(c.Watermark.Local()).AddDuration(-window)
+		if refetchFrom.String() < windowFrom.String() {
+			windowFrom = refetchFrom
+		}
+
+		cachedTo := windowFrom.Add(0, 0, -1)
+		if to.String() < cachedTo.String() {
+			cachedTo = to
+		}
+
+		if from.String() < windowFrom.String() && c.Covers(from, cachedTo) {
+			fetchFrom = windowFrom
+		}
+	}
+
+	if fetchFrom.String() > to.String() {
+		// e.g. an old month before the window
+		return c.Entries(from, to), nil
+	}
+
+	fetchedAt := time.Now()
+
+	entries, err := client.FetchEntriesBetween(fetchFrom, to)
+	if api.IsUnreachable(err) && c.Covers(from, to) {
+		logger.Warnf("mite is unreachable, using the entries cached at %s: %v", c.Watermark.Local().Format(time.RFC822), err)
+		return c.Entries(from, to), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("Fetched %d entries from %s, the entries before are cached", len(entries), fetchFrom)
+
+	c.Update(fetchFrom, to, entries, fetchedAt)
+
+	err = c.Save()
+	if err != nil {
+		logger.Warnf("Unable to write the cache %s: %v", path, err)
+	}
+
+	return c.Entries(from, to), nil
+}
+
+func timesheetFile(excelFilePath string) *export.XlFile {
+	if currentConfig.CurrentExportFile != nil {
+		return currentConfig.CurrentExportFile
+	}
+
+	return export.ExcelFile(excelFilePath)
 }
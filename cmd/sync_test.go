@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"github.com/leanovate/mite-go/domain"
+	"github.com/spf13/cobra"
+	"testing"
+)
+
+func newPushRangeCmd() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("month", "", "")
+	cmd.Flags().String("from", "", "")
+	cmd.Flags().String("to", "", "")
+
+	return cmd
+}
+
+func TestReadPushRangeDefaultsToTheCurrentMonth(t *testing.T) {
+	from, to, err := readPushRange(newPushRangeCmd())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	today := domain.Today()
+	first := firstOfMonth(today)
+
+	if from.String() != first.String() || to.String() != lastOfMonth(today).String() {
+		t.Fatalf("expected the current month, got %s to %s", from, to)
+	}
+
+	firstDay, err := domain.ParseLocalDate(first.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the range starts at midnight, rows on the first of the month are in it
+	if !inRange(firstDay, from, to) {
+		t.Errorf("a row on %s isn't in the range %s to %s", firstDay, from, to)
+	}
+
+	if !inRange(today, from, to) {
+		t.Errorf("today isn't in the range %s to %s", from, to)
+	}
+}
+
+func TestInRangeIgnoresTheTimeOfDay(t *testing.T) {
+	day, err := domain.ParseLocalDate(domain.Today().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !inRange(day, domain.Today(), domain.Today()) {
+		t.Errorf("%s isn't in the range of today", day)
+	}
+
+	if inRange(day.Add(0, 0, -1), day, day) || inRange(day.Add(0, 0, 1), day, day) {
+		t.Errorf("the days around %s are in its range", day)
+	}
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"mighty/api"
+	"mighty/config"
+	"mighty/reference"
+	"strings"
+)
+
+var (
+	trackerCmd = &cobra.Command{
+		Use:   "tracker",
+		Short: "Starts, stops and shows the mite tracker",
+		Long: `Starts, stops and shows the mite tracker, the stopwatch of mite.
+
+Start the tracker on an existing entry, or on a new entry of today. The project and service of a new entry are
+resolved by the Projects and Services sheets of the timesheet, like the rows of the month sheets:
+
+$ mighty tracker start --entry 123456
+$ mighty tracker start -p "ACME / Maintenance" -s Development "fixing the login"
+$ mighty tracker status
+$ mighty tracker stop
+
+Starting a tracker stops the one running before. Entries created by the tracker are added to the timesheet by the
+next pull.
+`,
+	}
+
+	trackerStartCmd = &cobra.Command{
+		Use:   "start [note]",
+		Short: "Starts the tracker on an existing or a new entry",
+		Args:  cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config.ReadCfg()
+
+			entryId, err := cmd.Flags().GetInt("entry")
+			if err != nil {
+				logger.Fatal("Unable to read the entry flag", err)
+			}
+
+			projectName, err := cmd.Flags().GetString("project")
+			if err != nil {
+				logger.Fatal("Unable to read the project flag", err)
+			}
+
+			serviceName, err := cmd.Flags().GetString("service")
+			if err != nil {
+				logger.Fatal("Unable to read the service flag", err)
+			}
+
+			client, err := createClientFromConfig()
+			if err != nil {
+				logger.Fatalf("Unable to create api client %v", err)
+			}
+
+			id := domain.NewTimeEntryId(entryId)
+			if entryId == 0 {
+				id, err = createTrackedEntry(cmd, client, projectName, serviceName, strings.Join(args, " "))
+				if err != nil {
+					logger.Fatal(err)
+				}
+			} else if projectName != "" || serviceName != "" || len(args) > 0 {
+				logger.Fatal("Either use --entry or --project, --service and the note of a new entry")
+			}
+
+			tracking, stopped, err := client.StartTracker(id)
+			if err != nil {
+				logger.Fatalf("Unable to start the tracker %v", err)
+			}
+
+			if stopped != nil {
+				fmt.Printf("Stopped [%s] at %s\n", stopped.Id, stopped.Minutes.String())
+			}
+
+			if tracking != nil {
+				fmt.Printf("Tracking [%s] since %s\n", tracking.Id, tracking.Since.Local().Format("15:04"))
+			}
+		},
+	}
+
+	trackerStopCmd = &cobra.Command{
+		Use:   "stop",
+		Short: "Stops the running tracker",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			config.ReadCfg()
+
+			client, err := createClientFromConfig()
+			if err != nil {
+				logger.Fatalf("Unable to create api client %v", err)
+			}
+
+			stopped, err := client.StopTracker()
+			if err != nil {
+				logger.Fatalf("Unable to stop the tracker %v", err)
+			}
+
+			if stopped == nil {
+				fmt.Println("No tracker is running.")
+				return
+			}
+
+			fmt.Printf("Stopped [%s] at %s\n", stopped.Id, stopped.Minutes.String())
+		},
+	}
+
+	trackerStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Shows the entry of the running tracker and its elapsed time",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			config.ReadCfg()
+
+			client, err := createClientFromConfig()
+			if err != nil {
+				logger.Fatalf("Unable to create api client %v", err)
+			}
+
+			tracking, entry, err := client.Tracker()
+			if err != nil {
+				logger.Fatalf("Unable to read the tracker %v", err)
+			}
+
+			if tracking == nil {
+				fmt.Println("No tracker is running.")
+				return
+			}
+
+			fmt.Printf("Tracking [%s] %s | %s | %s | %s\n", entry.Id, entry.Date,
+				reference.DisplayName(entry.CustomerName, entry.ProjectName), entry.ServiceName, entry.Note)
+			fmt.Printf("Running since %s, elapsed %s\n", tracking.Since.Local().Format("15:04"),
+				tracking.Minutes.String())
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(trackerCmd)
+	trackerCmd.AddCommand(trackerStartCmd, trackerStopCmd, trackerStatusCmd)
+	trackerStartCmd.Flags().Int("entry", 0, "id of the existing entry to track")
+	trackerStartCmd.Flags().StringP("project", "p", "", "project of the new entry, e.g. \"Customer / Project\"")
+	trackerStartCmd.Flags().StringP("service", "s", "", "service of the new entry")
+}
+
+// createTrackedEntry creates an empty entry of today to start the tracker on
+func createTrackedEntry(cmd *cobra.Command, client *api.Client, projectName, serviceName, note string) (domain.TimeEntryId, error) {
+	if projectName == "" || serviceName == "" {
+		return 0, errors.New("use --entry to track an existing entry or --project and --service to create a new one")
+	}
+
+	exportFile, err := openTimesheet(cmd)
+	if err != nil {
+		return 0, err
+	}
+
+	project, service, err := exportFile.ResolveReference(projectName, serviceName)
+	if err != nil {
+		return 0, err
+	}
+
+	entry := &domain.TimeEntry{
+		Date:      domain.Today(),
+		Minutes:   domain.NewMinutes(0),
+		Note:      note,
+		Billable:  service.Billable,
+		ProjectId: project.Id,
+		ServiceId: service.Id,
+	}
+
+	created, err := client.CreateEntry(entry)
+	if err != nil {
+		return 0, fmt.Errorf("unable to create the entry %v", err)
+	}
+
+	logger.Infof("Created [%s] %s | %s | %s | %s", created.Id, created.Date,
+		reference.DisplayName(created.CustomerName, created.ProjectName), created.ServiceName, created.Note)
+
+	return created.Id, nil
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/fsnotify/fsnotify"
+	"github.com/leanovate/mite-go/domain"
+	logger "github.com/sirupsen/logrus"
+	"mighty/api"
+	"mighty/export"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// watchDebounce is the quiet time after the last change of the timesheet before it is synced, saving a workbook
+	// causes several events in a row
+	watchDebounce = 2 * time.Second
+	// excelLockPrefix starts the name of the lock file excel creates next to an open workbook
+	excelLockPrefix = "~$"
+)
+
+// watchFile syncs the months of the timesheet which changed whenever it is saved, until the process is stopped
+func watchFile(excelFilePath string, opts syncOptions) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = watcher.Close() }()
+
+	// excel saves to a temporary file and renames it, which only the directory sees
+	err = watcher.Add(filepath.Dir(excelFilePath))
+	if err != nil {
+		return err
+	}
+
+	fingerprints, err := readFingerprints(excelFilePath)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Watching %s for changes, press Ctrl+C to stop", excelFilePath)
+
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if !isTimesheetEvent(event, excelFilePath) {
+				continue
+			}
+
+			logger.Debugf("%s changed: %s", excelFilePath, event.Op)
+			debounce = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			logger.Warnf("Watching %s failed: %v", excelFilePath, err)
+		case <-debounce:
+			debounce = nil
+			fingerprints = syncChangedMonths(excelFilePath, opts, fingerprints)
+		}
+	}
+}
+
+// isTimesheetEvent reports whether the event changed the timesheet itself, neither excel's lock file nor its
+// temporary files
+func isTimesheetEvent(event fsnotify.Event, excelFilePath string) bool {
+	name := filepath.Base(event.Name)
+	if strings.HasPrefix(name, excelLockPrefix) || name != filepath.Base(excelFilePath) {
+		return false
+	}
+
+	return event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) != 0
+}
+
+// syncChangedMonths pushes the months whose sheets differ from the given fingerprints and pulls them back if
+// anything was pushed, so the created entries get their ids. Pushes queued while mite was unreachable are replayed
+// first. It returns the fingerprints of the synced timesheet, so the saves of the sync itself don't trigger another
+// one. Failures are logged and retried with the next save, pushes queued as mite is unreachable are not.
+func syncChangedMonths(excelFilePath string, opts syncOptions, fingerprints map[string]string) map[string]string {
+	current, err := readFingerprints(excelFilePath)
+	if err != nil {
+		// e.g. the file is replaced in the middle of a save
+		logger.Warnf("Unable to read %s, waiting for the next save: %v", excelFilePath, err)
+		return fingerprints
+	}
+
+	changed := changedMonths(current, fingerprints)
+	if len(changed) == 0 {
+		// e.g. the save of the previous sync
+		logger.Debugf("%s is unchanged", excelFilePath)
+		return current
+	}
+
+	if api.QueueExists(excelFilePath) {
+		queued, err := api.ReadQueue(excelFilePath)
+		if err != nil {
+			logger.Errorf("Unable to read the queue of %s: %v", excelFilePath, err)
+			return fingerprints
+		}
+
+		resume, err := flushQueue(excelFilePath)
+		if err != nil {
+			logger.Errorf("Unable to replay the queue of %s: %v", excelFilePath, err)
+			return fingerprints
+		}
+
+		opts.resume = opts.resume || resume
+
+		// the replay writes the ids of the created entries
+		current, err = readFingerprints(excelFilePath)
+		if err != nil {
+			logger.Warnf("Unable to read %s, waiting for the next save: %v", excelFilePath, err)
+			return fingerprints
+		}
+
+		// the months of the replayed operations are synced as well, so their rows are pulled
+		synced := make(map[string]string, len(fingerprints))
+		for sheetName, fingerprint := range fingerprints {
+			synced[sheetName] = fingerprint
+		}
+
+		for _, op := range queued {
+			if date, err := domain.ParseLocalDate(op.Date); err == nil {
+				delete(synced, export.MonthSheetName(date))
+			}
+		}
+
+		changed = changedMonths(current, synced)
+	}
+
+	var failed []string
+
+	for _, month := range changed {
+		sheetName := export.MonthSheetName(month)
+
+		logger.Infof("'%s' changed, syncing it", sheetName)
+
+		monthOpts := opts
+		monthOpts.from = month
+		monthOpts.to = lastOfMonth(month)
+
+		conflicts, pushed, err := pushToFile(excelFilePath, monthOpts)
+		if errors.Is(err, errQueued) {
+			// the queue is replayed with the next save or sync
+			logger.Warnf("'%s': %v", sheetName, err)
+			continue
+		}
+		if err != nil {
+			logger.Errorf("Unable to sync '%s': %v", sheetName, err)
+			failed = append(failed, sheetName)
+			continue
+		}
+
+		// leave the open workbook alone unless there are new ids or conflicts to write
+		if pushed == 0 && len(conflicts) == 0 {
+			logger.Infof("'%s' is up-to-date", sheetName)
+			continue
+		}
+
+		err = pullRange(timesheetFile(excelFilePath), monthOpts.from, monthOpts.to, conflicts, monthOpts.from)
+		if err != nil {
+			logger.Errorf("Unable to update '%s' in %s: %v", sheetName, excelFilePath, err)
+			failed = append(failed, sheetName)
+			continue
+		}
+
+		err = api.RemoveJournal(excelFilePath)
+		if err != nil {
+			logger.Errorf("Unable to remove the journal of %s: %v", excelFilePath, err)
+		}
+	}
+
+	synced, err := readFingerprints(excelFilePath)
+	if err != nil {
+		logger.Warnf("Unable to read %s: %v", excelFilePath, err)
+		synced = current
+	}
+
+	// failed months are synced again with the next save
+	for _, sheetName := range failed {
+		synced[sheetName] = fingerprints[sheetName]
+	}
+
+	return synced
+}
+
+// changedMonths returns the months whose sheets differ from the fingerprints in order
+func changedMonths(current, fingerprints map[string]string) []domain.LocalDate {
+	var changed []domain.LocalDate
+
+	for sheetName, fingerprint := range current {
+		if month, err := export.ParseMonthSheetName(sheetName); err == nil && fingerprint != fingerprints[sheetName] {
+			changed = append(changed, month)
+		}
+	}
+
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].Before(changed[j])
+	})
+
+	return changed
+}
+
+func readFingerprints(excelFilePath string) (map[string]string, error) {
+	exportFile := timesheetFile(excelFilePath)
+
+	err := exportFile.ReloadFromDisk()
+	if err != nil {
+		return nil, err
+	}
+
+	return exportFile.MonthFingerprints()
+}
@@ -3,16 +3,31 @@ package config
 import (
 	"fmt"
 	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"mighty/export"
 	"os"
+	"time"
 )
 
 type MightyConfig struct {
-	MiteUrl           string `mapstructure:"url"`
-	Token             string `mapstructure:"token"`
-	EnableDebug       bool   `mapstructure:"debug"`
-	EntriesHistory    string `mapstructure:"history"`
+	MiteUrl           string        `mapstructure:"url"`
+	Token             string        `mapstructure:"token"`
+	TokenCommand      string        `mapstructure:"token_command"`
+	TokenFile         string        `mapstructure:"token_file"`
+	EnableDebug       bool          `mapstructure:"debug"`
+	EntriesHistory    string        `mapstructure:"history"`
+	Concurrency       int           `mapstructure:"concurrency"`
+	RateLimit         float64       `mapstructure:"rate_limit"`
+	Retries           int           `mapstructure:"retries"`
+	RetryMaxWait      time.Duration `mapstructure:"retry_max_wait"`
+	RequireNote       bool          `mapstructure:"require_note"`
+	DailyTarget       time.Duration `mapstructure:"daily_target"`
+	WeeklyTarget      time.Duration `mapstructure:"weekly_target"`
+	OvertimeStart     string        `mapstructure:"overtime_start"`
+	CacheWindow       string        `mapstructure:"cache_window"`
+	Timesheet         string        `mapstructure:"timesheet"`
+	Profile           string        `mapstructure:"-"`
 	CurrentExportFile *export.XlFile
 }
 
@@ -30,6 +45,14 @@ var (
 		Token:          "<get_your_token>",
 		EnableDebug:    false,
 		EntriesHistory: "4w",
+		Concurrency:    4,
+		RateLimit:      5,
+		Retries:        3,
+		RetryMaxWait:   30 * time.Second,
+		DailyTarget:    8 * time.Hour,
+		WeeklyTarget:   40 * time.Hour,
+		CacheWindow:    "2w",
+		Timesheet:      "~/entries.xlsx",
 	}
 )
 
@@ -56,8 +79,40 @@ func SetupCfg(cfgFile string, generateCfg bool) {
 
 		v.SetDefault("url", DefaultConfig.MiteUrl)
 		v.SetDefault("token", DefaultConfig.Token)
+		v.SetDefault("token_command", DefaultConfig.TokenCommand)
+		v.SetDefault("token_file", DefaultConfig.TokenFile)
+		// never write a token given by the environment
+		v.Set("token", DefaultConfig.Token)
+		v.SetConfigPermissions(0600)
 		v.SetDefault("debug", DefaultConfig.EnableDebug)
 		v.SetDefault("history", DefaultConfig.EntriesHistory)
+		v.SetDefault("concurrency", DefaultConfig.Concurrency)
+		v.SetDefault("rate_limit", DefaultConfig.RateLimit)
+		v.SetDefault("retries", DefaultConfig.Retries)
+		v.SetDefault("retry_max_wait", DefaultConfig.RetryMaxWait.String())
+		v.SetDefault("require_note", DefaultConfig.RequireNote)
+		v.SetDefault("daily_target", DefaultConfig.DailyTarget.String())
+		v.SetDefault("weekly_target", DefaultConfig.WeeklyTarget.String())
+		v.SetDefault("overtime_start", DefaultConfig.OvertimeStart)
+		v.SetDefault("cache_window", DefaultConfig.CacheWindow)
+		v.SetDefault("timesheet", DefaultConfig.Timesheet)
+
+		if profile != "" {
+			if _, err := os.Stat(cfgFile); err == nil {
+				if err = addProfile(cfgFile, profile); err != nil {
+					logger.Fatal(err)
+				}
+
+				logger.Infof("Added profile %s to %s\n", profile, cfgFile)
+				return
+			}
+
+			if err := validateProfileName(profile); err != nil {
+				logger.Fatal(err)
+			}
+
+			v.SetDefault(profileKey(profile), profileDefaults(profile))
+		}
 
 		if err := v.SafeWriteConfigAs(cfgFile); err != nil {
 			switch err.(type) {
@@ -72,16 +127,42 @@ func SetupCfg(cfgFile string, generateCfg bool) {
 		if cfgFile != "" {
 			v.SetConfigFile(cfgFile)
 		} else {
-			v.SetEnvPrefix("MIGHTY")
-			v.AutomaticEnv()
+			v.SetConfigName(DefaultCfgFile)
+		}
+
+		v.SetEnvPrefix("MIGHTY")
+		v.AutomaticEnv()
+
+		// the token may be given by the environment only, AutomaticEnv just covers the keys of the config file
+		for _, key := range tokenKeys {
+			_ = v.BindEnv(key)
 		}
 		for _, p := range defaultCfgSearchPaths {
 			v.AddConfigPath(p)
 		}
+
+		// config files generated by older versions lack these
+		v.SetDefault("concurrency", DefaultConfig.Concurrency)
+		v.SetDefault("rate_limit", DefaultConfig.RateLimit)
+		v.SetDefault("retries", DefaultConfig.Retries)
+		v.SetDefault("retry_max_wait", DefaultConfig.RetryMaxWait.String())
+		v.SetDefault("daily_target", DefaultConfig.DailyTarget.String())
+		v.SetDefault("weekly_target", DefaultConfig.WeeklyTarget.String())
+		v.SetDefault("cache_window", DefaultConfig.CacheWindow)
+		v.SetDefault("timesheet", DefaultConfig.Timesheet)
 	}
 
 }
 
+// BindFlag lets the flag override the config key whenever it is given
+func BindFlag(key string, flag *pflag.Flag) {
+	if err := v.BindPFlag(key, flag); err != nil {
+		logger.Fatalf("Unable to bind the flag --%s to %s: %v", flag.Name, key, err)
+	}
+
+	boundFlags[key] = flag
+}
+
 func ReadCfg() {
 	logger.Infof("Using config file %s\n", v.ConfigFileUsed())
 
@@ -90,15 +171,29 @@ func ReadCfg() {
 If it doesn't exist, use 'mighty gen --config' to create a config file`, err)
 	}
 
+	if err := applyProfile(); err != nil {
+		logger.Fatal(err)
+	}
+
 	if err := v.Unmarshal(&CurrentConfig); err != nil {
 		logger.Fatalf("Unable to read the config %v", err)
 	}
 
+	CurrentConfig.Profile = profile
+
 	if CurrentConfig.EnableDebug {
 		logger.SetLevel(logger.DebugLevel)
 	} else {
 		logger.SetLevel(logger.InfoLevel)
 	}
 
-	logger.Debugf("Config: %v", v.AllSettings())
+	if err := resolveToken(&CurrentConfig); err != nil {
+		logger.Fatal(err)
+	}
+
+	if CurrentConfig.Token != "" {
+		logger.AddHook(redactHook{CurrentConfig.Token})
+	}
+
+	logger.Debugf("Config: %v", Redacted(v.AllSettings()))
 }
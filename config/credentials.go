@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	logger "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const (
+	// TokenEnv overrides every other source of the token
+	TokenEnv = "MIGHTY_TOKEN"
+	// redacted replaces the token wherever it could be logged or printed
+	redacted = "<redacted>"
+)
+
+// tokenKeys are the settings which hold a token or lead to one, they are read from MIGHTY_<KEY> as well
+var tokenKeys = []string{"token", "token_command", "token_file"}
+
+// resolveToken sets the token of the config from the first source given of MIGHTY_TOKEN, token_command, token_file
+// and token. A profile replaces the token sources of the top level, see applyProfile.
+func resolveToken(cfg *MightyConfig) error {
+	if token, ok := os.LookupEnv(TokenEnv); ok && token != "" {
+		cfg.Token = strings.TrimSpace(token)
+		tokenSource = "env " + TokenEnv
+		return nil
+	}
+
+	tokenSource = "token of " + source("token")
+
+	switch {
+	case cfg.TokenCommand != "":
+		token, err := runTokenCommand(cfg.TokenCommand)
+		if err != nil {
+			return err
+		}
+
+		cfg.Token = token
+		tokenSource = "token_command of " + source("token_command")
+	case cfg.TokenFile != "":
+		token, err := readTokenFile(cfg.TokenFile)
+		if err != nil {
+			return err
+		}
+
+		cfg.Token = token
+		tokenSource = "token_file of " + source("token_file")
+	}
+
+	return nil
+}
+
+// runTokenCommand runs the command by the shell and returns the first line of its output, e.g. of `pass show mite`
+func runTokenCommand(command string) (string, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(shell, flag, command)
+	// e.g. a pinentry asking for the passphrase
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	// the output is left out of the errors, it is the token after all
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("token_command %q failed: %v", command, err)
+	}
+
+	token, err := bufio.NewReader(&stdout).ReadString('\n')
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("token_command %q printed no token: %v", command, err)
+	}
+
+	return token, nil
+}
+
+// readTokenFile reads the token from a file which only its owner may read
+func readTokenFile(file string) (string, error) {
+	path, err := homedir.Expand(file)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read token_file: %v", err)
+	}
+
+	// windows doesn't have permission bits
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("token_file %s is accessible by others (%s), restrict it with `chmod 600 %s`",
+			path, info.Mode().Perm(), path)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read token_file: %v", err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", errors.New("token_file " + path + " is empty")
+	}
+
+	return token, nil
+}
+
+// Redacted returns a copy of the settings with every token replaced, including the ones of the profiles
+func Redacted(settings map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(settings))
+
+	for key, value := range settings {
+		if nested, ok := value.(map[string]interface{}); ok {
+			copied[key] = Redacted(nested)
+		} else if key == "token" && value != "" {
+			copied[key] = redacted
+		} else {
+			copied[key] = value
+		}
+	}
+
+	return copied
+}
+
+// redactHook removes the token from every log entry, e.g. of errors quoting a request
+type redactHook struct {
+	token string
+}
+
+func (h redactHook) Levels() []logger.Level {
+	return logger.AllLevels
+}
+
+func (h redactHook) Fire(entry *logger.Entry) error {
+	entry.Message = strings.ReplaceAll(entry.Message, h.token, redacted)
+
+	for key, value := range entry.Data {
+		if s, ok := value.(string); ok {
+			entry.Data[key] = strings.ReplaceAll(s, h.token, redacted)
+		}
+	}
+
+	return nil
+}
+
+// String keeps the token out of the printed config
+func (c MightyConfig) String() string {
+	c.Token = redacted
+	c.CurrentExportFile = nil
+
+	// without the String method
+	type plain MightyConfig
+
+	return fmt.Sprintf("%+v", plain(c))
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Setting is a setting of the effective config along with where its value comes from
+type Setting struct {
+	Key    string
+	Value  interface{}
+	Source string
+}
+
+var (
+	// boundFlags are the flags overriding settings, see BindFlag
+	boundFlags = make(map[string]*pflag.Flag)
+	// profileKeys are the settings of the selected profile
+	profileKeys = make(map[string]bool)
+	// tokenSource tells where the token of the current config comes from
+	tokenSource string
+)
+
+// Path returns the config file in use, either given by --config or found in the home or working directory
+func Path() (string, error) {
+	if err := v.ReadInConfig(); err != nil {
+		return "", err
+	}
+
+	return v.ConfigFileUsed(), nil
+}
+
+// Settings returns the effective settings of the config read by ReadCfg ordered by key, the token is redacted
+func Settings() []Setting {
+	var settings []Setting
+
+	for _, key := range v.AllKeys() {
+		if strings.HasPrefix(key, profilesKey+".") {
+			continue
+		}
+
+		setting := Setting{Key: key, Value: v.Get(key), Source: source(key)}
+		if key == "token" {
+			setting.Value = redacted
+			setting.Source = tokenSource
+		}
+
+		settings = append(settings, setting)
+	}
+
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Key < settings[j].Key
+	})
+
+	return settings
+}
+
+func source(key string) string {
+	if flag, ok := boundFlags[key]; ok && flag.Changed {
+		return "flag --" + flag.Name
+	}
+
+	if env := envKey(key); os.Getenv(env) != "" {
+		return "env " + env
+	}
+
+	switch {
+	case profileKeys[key]:
+		return "profile " + profile
+	case v.InConfig(key):
+		return "config"
+	}
+
+	return "default"
+}
+
+func envKey(key string) string {
+	return "MIGHTY_" + strings.ToUpper(key)
+}
+
+// Set writes the setting to the config file, to the selected profile if there is one. It returns the written file.
+func Set(key, value string) (string, error) {
+	typ, ok := settingTypes()[key]
+	if !ok {
+		return "", fmt.Errorf("unknown setting %s, use one of %s", key, strings.Join(SettingKeys(), ", "))
+	}
+
+	parsed, err := parseSetting(typ, value)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse %s %q: %v", key, value, err)
+	}
+
+	cfgFile, err := Path()
+	if err != nil {
+		return "", err
+	}
+
+	target := key
+	if profile != "" {
+		if err = validateProfileName(profile); err != nil {
+			return "", err
+		}
+
+		target = profileKey(profile) + "." + key
+	}
+
+	existing := viper.New()
+	existing.SetConfigFile(cfgFile)
+
+	if err = existing.ReadInConfig(); err != nil {
+		return "", err
+	}
+
+	existing.Set(target, parsed)
+
+	return cfgFile, existing.WriteConfig()
+}
+
+// SettingKeys returns the keys of all settings in order
+func SettingKeys() []string {
+	var keys []string
+	for key := range settingTypes() {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// settingTypes returns the type of every setting by its key
+func settingTypes() map[string]reflect.Type {
+	types := make(map[string]reflect.Type)
+
+	t := reflect.TypeOf(MightyConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("mapstructure"); key != "" && key != "-" {
+			types[key] = t.Field(i).Type
+		}
+	}
+
+	return types
+}
+
+// parseSetting checks the value fits the setting, durations are kept as written since viper parses them
+func parseSetting(typ reflect.Type, value string) (interface{}, error) {
+	if typ == reflect.TypeOf(time.Duration(0)) {
+		_, err := time.ParseDuration(value)
+		return value, err
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(value)
+	case reflect.Int:
+		return strconv.Atoi(value)
+	case reflect.Float64:
+		return strconv.ParseFloat(value, 64)
+	}
+
+	return value, nil
+}
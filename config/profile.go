@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+const (
+	// ProfileEnv selects the profile unless --profile is given
+	ProfileEnv = "MIGHTY_PROFILE"
+	// profilesKey holds the named profiles of the config, every profile overrides the top level settings
+	profilesKey = "profiles"
+)
+
+// profile is the selected profile, empty for the top level settings
+var profile string
+
+// UseProfile selects the named profile of the config, an empty name falls back to MIGHTY_PROFILE
+func UseProfile(name string) {
+	if name == "" {
+		name = os.Getenv(ProfileEnv)
+	}
+
+	profile = name
+}
+
+func profileKey(name string) string {
+	return profilesKey + "." + name
+}
+
+// profileDefaults are the settings of a generated profile, each profile gets its own timesheet so the entries of
+// different accounts never mix
+func profileDefaults(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"url":       DefaultConfig.MiteUrl,
+		"token":     DefaultConfig.Token,
+		"history":   DefaultConfig.EntriesHistory,
+		"timesheet": fmt.Sprintf("~/entries-%s.xlsx", name),
+	}
+}
+
+func validateProfileName(name string) error {
+	// viper nests keys by dots
+	if strings.ContainsAny(name, ". ") {
+		return fmt.Errorf("profile %q must neither contain dots nor spaces", name)
+	}
+
+	return nil
+}
+
+// applyProfile overrides the top level settings by the ones of the selected profile, flags still take precedence
+func applyProfile() error {
+	if profile == "" {
+		return nil
+	}
+
+	if err := validateProfileName(profile); err != nil {
+		return err
+	}
+
+	settings := v.Sub(profileKey(profile))
+	if settings == nil {
+		return fmt.Errorf("profile %s isn't in %s, add it with `mighty gen config --profile %s`", profile,
+			v.ConfigFileUsed(), profile)
+	}
+
+	logger.Infof("Using profile %s", profile)
+
+	merged := settings.AllSettings()
+
+	// a profile with a token source of its own replaces all inherited ones, the token of another account must never
+	// win over it
+	if hasTokenSource(merged) {
+		for _, key := range tokenKeys {
+			if _, ok := merged[key]; !ok {
+				merged[key] = ""
+			}
+		}
+	}
+
+	for key := range merged {
+		profileKeys[key] = true
+	}
+
+	return v.MergeConfigMap(merged)
+}
+
+// addProfile adds a profile with default settings to an existing config file, the other settings are kept
+func addProfile(cfgFile, name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+
+	existing := viper.New()
+	existing.SetConfigFile(cfgFile)
+
+	if err := existing.ReadInConfig(); err != nil {
+		return err
+	}
+
+	if existing.IsSet(profileKey(name)) {
+		return fmt.Errorf("profile %s already exists in %s, nope, I won't override it", name, cfgFile)
+	}
+
+	existing.Set(profileKey(name), profileDefaults(name))
+
+	return existing.WriteConfig()
+}
+
+func hasTokenSource(settings map[string]interface{}) bool {
+	for _, key := range tokenKeys {
+		if _, ok := settings[key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
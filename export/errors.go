@@ -0,0 +1,50 @@
+package export
+
+import (
+	"fmt"
+)
+
+// CellError is a failure to read or write a single cell of the workbook
+type CellError struct {
+	Sheet string
+	Axis  string
+	Err   error
+}
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("'%s'!%s: %v", e.Sheet, e.Axis, e.Err)
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
+
+// SheetError is a failure concerning a whole sheet, e.g. a sheet which is missing or can't be read
+type SheetError struct {
+	Sheet string
+	Err   error
+}
+
+func (e *SheetError) Error() string {
+	return fmt.Sprintf("sheet '%s': %v", e.Sheet, e.Err)
+}
+
+func (e *SheetError) Unwrap() error {
+	return e.Err
+}
+
+func cellError(sheetName, axis string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &CellError{sheetName, axis, err}
+}
+
+func sheetError(sheetName string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &SheetError{sheetName, err}
+}
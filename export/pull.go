@@ -0,0 +1,482 @@
+package export
+
+import (
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	log "github.com/sirupsen/logrus"
+	"github.com/xuri/excelize/v2"
+	"mighty/merge"
+	"mighty/reference"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// conflictPromptLimit is the maximum length of a data validation prompt accepted by excel
+	conflictPromptLimit = 255
+	// defaultRowHeight is the height of a row holding a single line
+	defaultRowHeight = 15
+)
+
+// monthRow is a row of a month sheet which carries an entry id
+type monthRow struct {
+	sheet  string
+	number int
+	id     domain.TimeEntryId
+	// date is only set for rows with a parsable date
+	date  domain.LocalDate
+	dated bool
+}
+
+// entryStyles are the styles of the cells owned by mighty
+type entryStyles struct {
+	notes    int
+	date     int
+	time     int
+	conflict int
+	pending  int
+	locked   int
+	// unlocked is the plain style of the cells and columns the user may fill under protection
+	unlocked int
+}
+
+// openOrCreate loads the workbook from disk once, so the pull updates it instead of replacing it
+func (xlx *XlFile) openOrCreate() error {
+	if xlx.loaded {
+		return nil
+	}
+
+	if _, err := os.Stat(xlx.fileName); os.IsNotExist(err) {
+		log.Debugf("%s doesn't exist yet, creating it", xlx.fileName)
+		return nil
+	}
+
+	return xlx.ReloadFromDisk()
+}
+
+func (xlx *XlFile) newEntryStyles() (*entryStyles, error) {
+	var err error
+
+	styles := &entryStyles{}
+	for _, s := range []struct {
+		id    *int
+		style *excelize.Style
+	}{
+		{&styles.notes, &excelize.Style{Alignment: entryAlignment, Protection: entryUnlocked}},
+		{&styles.date, &excelize.Style{CustomNumFmt: &entryDateFormat, Alignment: entryAlignment, Protection: entryUnlocked}},
+		{&styles.time, &excelize.Style{CustomNumFmt: &entryTimeFormat, Alignment: entryAlignment, Protection: entryUnlocked}},
+		{&styles.conflict, &excelize.Style{Fill: entryConflictFill, Alignment: entryAlignment, Protection: entryUnlocked}},
+		{&styles.pending, &excelize.Style{Fill: entryPendingFill, Alignment: entryAlignment, Protection: entryUnlocked}},
+		{&styles.locked, &excelize.Style{Fill: entryLockedFill, Font: entryLockedFont, Alignment: entryAlignment, Protection: entryLocked}},
+		{&styles.unlocked, &excelize.Style{Protection: entryUnlocked}},
+	} {
+		*s.id, err = xlx.file.NewStyle(s.style)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return styles, nil
+}
+
+// columnStyle returns the style of an owned column of the month sheets
+func (s *entryStyles) columnStyle(colName string) int {
+	switch colName {
+	case "A":
+		return s.date
+	case "E":
+		return s.time
+	default:
+		return s.notes
+	}
+}
+
+// scanMonthSheets returns the rows of all month sheets which carry an entry id
+func (xlx *XlFile) scanMonthSheets() (map[domain.TimeEntryId]monthRow, error) {
+	rows, err := xlx.scanMonthRows()
+	if err != nil {
+		return nil, err
+	}
+
+	rowsById := make(map[domain.TimeEntryId]monthRow, len(rows))
+	for _, r := range rows {
+		rowsById[r.id] = r
+	}
+
+	return rowsById, nil
+}
+
+// scanMonthRows returns the rows of all month sheets which carry an entry id in workbook order, rows sharing an
+// id included
+func (xlx *XlFile) scanMonthRows() ([]monthRow, error) {
+	var monthRows []monthRow
+
+	for _, month := range xlx.MonthSheets() {
+		sheetName := MonthSheetName(month)
+
+		rows, err := xlx.file.GetRows(sheetName)
+		if err != nil {
+			return nil, sheetError(sheetName, err)
+		}
+
+		for rIx, row := range rows {
+			// skip header
+			if rIx < 2 || len(row) < 7 || row[6] == "" {
+				continue
+			}
+
+			id, err := domain.ParseTimeEntryId(row[6])
+			if err != nil {
+				log.Warnf("'%s'!G%d: ignoring the unparsable entry id %q", sheetName, rIx+1, row[6])
+				continue
+			}
+
+			r := monthRow{sheet: sheetName, number: rIx + 1, id: id}
+			if date, err := domain.ParseLocalDate(row[0]); err == nil {
+				r.date = date
+				r.dated = true
+			}
+
+			monthRows = append(monthRows, r)
+		}
+	}
+
+	return monthRows, nil
+}
+
+// removeRows removes the given rows of the sheets, the rows below move up along with the user's cells
+func (xlx *XlFile) removeRows(rowsBySheet map[string][]int) error {
+	for sheetName, rows := range rowsBySheet {
+		sort.Sort(sort.Reverse(sort.IntSlice(rows)))
+
+		for _, row := range rows {
+			err := xlx.file.RemoveRow(sheetName, row)
+			if err != nil {
+				return sheetError(sheetName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeRowsFrom removes the rows from the given one to the end of the sheet, e.g. left over from a longer list
+func (xlx *XlFile) removeRowsFrom(sheetName string, from int) error {
+	rows, err := xlx.file.GetRows(sheetName)
+	if err != nil {
+		return sheetError(sheetName, err)
+	}
+
+	for row := len(rows); row >= from; row-- {
+		err = xlx.file.RemoveRow(sheetName, row)
+		if err != nil {
+			return sheetError(sheetName, err)
+		}
+	}
+
+	return nil
+}
+
+// nextRow returns the first row after the content of the sheet
+func (xlx *XlFile) nextRow(sheetName string) (int, error) {
+	rows, err := xlx.file.GetRows(sheetName)
+	if err != nil {
+		return 0, sheetError(sheetName, err)
+	}
+
+	if len(rows) < 2 {
+		return 3, nil
+	}
+
+	return len(rows) + 1, nil
+}
+
+// writeEntryRow writes the owned cells of an entry row, the cells to the right belong to the user and stay untouched.
+// The row is only styled if it is new or its state changed, e.g. it got locked, so the user's formatting is kept.
+func (xlx *XlFile) writeEntryRow(sheetName string, row int, entry *domain.TimeEntry, styles *entryStyles, newRow bool) error {
+	axis := fmt.Sprintf("A%d", row)
+
+	style, err := xlx.file.GetCellStyle(sheetName, axis)
+	if err != nil {
+		return cellError(sheetName, axis, err)
+	}
+
+	err = xlx.WriteEntry(entry.Id.String(), sheetName, row, []string{
+		entry.Date.String(),
+		reference.DisplayName(entry.CustomerName, entry.ProjectName),
+		entry.ServiceName,
+		strconv.FormatBool(entry.Billable),
+		entryTime(entry.Minutes),
+		entry.Note,
+	})
+	if err != nil {
+		return err
+	}
+
+	// rows flagged by an earlier sync get their plain style back, open conflicts are flagged again by the caller
+	flagged := style == styles.locked || style == styles.conflict || style == styles.pending
+
+	switch {
+	case entry.Locked && style != styles.locked:
+		err = xlx.file.SetCellStyle(sheetName, axis, fmt.Sprintf("G%d", row), styles.locked)
+		if err != nil {
+			return cellError(sheetName, axis, err)
+		}
+	case !entry.Locked && (newRow || flagged):
+		for _, colName := range []string{"A", "B", "C", "D", "E", "F", "G"} {
+			cell := fmt.Sprintf("%s%d", colName, row)
+
+			err = xlx.file.SetCellStyle(sheetName, cell, cell, styles.columnStyle(colName))
+			if err != nil {
+				return cellError(sheetName, cell, err)
+			}
+		}
+	}
+
+	// fit cell row height, based on the default height as the row may have been fitted by an earlier pull
+	count := strings.Count(entry.Note, "\n")
+	if count > 1 {
+		err = xlx.file.SetRowHeight(sheetName, row, defaultRowHeight*float64(count))
+		if err != nil {
+			return sheetError(sheetName, err)
+		}
+	}
+
+	return nil
+}
+
+// newMonthSheet creates the sheet of a month with the column styles for the rows to come, the columns of the user
+// are unlocked, so they stay editable once the month is protected
+func (xlx *XlFile) newMonthSheet(sheetName string, styles *entryStyles) error {
+	xlx.file.NewSheet(sheetName)
+
+	for _, colName := range []string{"A", "B", "C", "D", "E", "F", "G"} {
+		err := xlx.file.SetColStyle(sheetName, colName, styles.columnStyle(colName))
+		if err != nil {
+			return sheetError(sheetName, err)
+		}
+	}
+
+	return sheetError(sheetName, xlx.file.SetColStyle(sheetName, "H:XFD", styles.unlocked))
+}
+
+// fitColumns adjusts the width of the owned columns to their content, only done for new sheets so the user's
+// widths are kept
+func (xlx *XlFile) fitColumns(sheetName string) error {
+	cols, err := xlx.file.GetCols(sheetName)
+	if err != nil {
+		return sheetError(sheetName, err)
+	}
+
+	for colIx, col := range cols {
+		maxColWidth := 0
+
+		for _, rowCell := range col {
+			cellWidth := utf8.RuneCountInString(rowCell)
+
+			if cellWidth > maxColWidth {
+				maxColWidth = cellWidth
+			}
+		}
+
+		colName, err := excelize.ColumnNumberToName(colIx + 1)
+		if err != nil {
+			return sheetError(sheetName, err)
+		}
+
+		if maxColWidth > excelize.MaxColumnWidth {
+			maxColWidth = 150
+		} else {
+			maxColWidth += 5
+		}
+
+		err = xlx.file.SetColWidth(sheetName, colName, colName, float64(maxColWidth))
+		if err != nil {
+			return sheetError(sheetName, err)
+		}
+	}
+
+	return nil
+}
+
+// writeConflictPrompt flags the note cell of a conflicting row with a prompt shown when the cell is selected.
+// Unlike a comment the prompt can be removed again once the conflict is resolved.
+func (xlx *XlFile) writeConflictPrompt(sheetName string, row int, c merge.Conflict) error {
+	remote := "deleted in mite"
+	if c.Remote != nil {
+		remote = fmt.Sprintf("mite has %s %s | %s | %s | %s", c.Remote.Date,
+			reference.DisplayName(c.Remote.CustomerName, c.Remote.ProjectName), c.Remote.ServiceName,
+			entryTime(c.Remote.Minutes), c.Remote.Note)
+	}
+
+	prompt := []rune(fmt.Sprintf("%s. Use `mighty sync --prefer local|remote` to resolve it.", remote))
+	if len(prompt) > conflictPromptLimit {
+		prompt = append(prompt[:conflictPromptLimit-1], '…')
+	}
+
+	dv := excelize.NewDataValidation(true)
+	dv.SetSqref(fmt.Sprintf("F%d", row))
+	dv.SetInput("Conflict", string(prompt))
+
+	return cellError(sheetName, fmt.Sprintf("F%d", row), xlx.file.AddDataValidation(sheetName, dv))
+}
+
+// clearPrompt removes the flag of a previous sync from the note cell, either a conflict or a pending change
+func (xlx *XlFile) clearPrompt(sheetName string, row int) error {
+	return cellError(sheetName, fmt.Sprintf("F%d", row), xlx.file.DeleteDataValidation(sheetName, fmt.Sprintf("F%d", row)))
+}
+
+// MarkPending flags the rows whose changes are queued until mite is reachable again and saves the workbook, the
+// pull after the changes reached mite removes the flags
+func (xlx *XlFile) MarkPending(rows []Row) error {
+	styles, err := xlx.newEntryStyles()
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		err = xlx.file.SetCellStyle(row.Sheet, fmt.Sprintf("A%d", row.Number), fmt.Sprintf("F%d", row.Number), styles.pending)
+		if err != nil {
+			return cellError(row.Sheet, fmt.Sprintf("A%d", row.Number), err)
+		}
+
+		axis := fmt.Sprintf("F%d", row.Number)
+
+		err = xlx.file.DeleteDataValidation(row.Sheet, axis)
+		if err != nil {
+			return cellError(row.Sheet, axis, err)
+		}
+
+		dv := excelize.NewDataValidation(true)
+		dv.SetSqref(axis)
+		dv.SetInput("Pending", "Queued while mite was unreachable, it is pushed by the next sync or `mighty queue flush`.")
+
+		err = xlx.file.AddDataValidation(row.Sheet, dv)
+		if err != nil {
+			return cellError(row.Sheet, axis, err)
+		}
+	}
+
+	return xlx.SaveToDisk()
+}
+
+// adoptRow finds the row of an entry which was created by the last push, it has no id yet but the same values
+func adoptRow(pending []Row, claimed map[int]bool, entry *domain.TimeEntry) (int, bool) {
+	for _, row := range pending {
+		e := row.Entry
+		if claimed[row.Number] || e.Date.String() != entry.Date.String() || e.Minutes.Value() != entry.Minutes.Value() ||
+			e.Note != entry.Note || e.ProjectId != entry.ProjectId || e.ServiceId != entry.ServiceId {
+			continue
+		}
+
+		claimed[row.Number] = true
+
+		return row.Number, true
+	}
+
+	return 0, false
+}
+
+// AdoptCreated writes the ids of entries created from rows without id, e.g. by the replay of the offline queue, into
+// their rows along with their snapshots and saves the workbook. The other rows are left alone, they may hold changes
+// which are yet to be pushed. It returns the entries without a row of the same values, e.g. as it was changed since.
+func (xlx *XlFile) AdoptCreated(entries []*domain.TimeEntry) ([]*domain.TimeEntry, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	styles, err := xlx.newEntryStyles()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := xlx.ReadSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	pendingRows := make(map[string][]Row)
+	claimedRows := make(map[string]map[int]bool)
+
+	var missing []*domain.TimeEntry
+
+	for _, entry := range entries {
+		month := MonthSheetName(entry.Date)
+
+		if _, ok := claimedRows[month]; !ok && xlx.file.GetSheetIndex(month) >= 0 {
+			rows, _, err := xlx.ReadAllEntriesBySheet(month)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, row := range rows {
+				if row.Entry.Id == 0 {
+					pendingRows[month] = append(pendingRows[month], row)
+				}
+			}
+
+			claimedRows[month] = make(map[int]bool)
+		}
+
+		row, ok := adoptRow(pendingRows[month], claimedRows[month], entry)
+		if !ok {
+			missing = append(missing, entry)
+			continue
+		}
+
+		log.Debugf("[%s] entry was created from '%s'!%d", entry.Id, month, row)
+
+		err = xlx.writeEntryRow(month, row, entry, styles, false)
+		if err != nil {
+			return nil, err
+		}
+
+		err = xlx.clearPrompt(month, row)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots[entry.Id] = merge.NewSnapshot(entry)
+	}
+
+	err = xlx.saveSnapshots(snapshots)
+	if err != nil {
+		return nil, err
+	}
+
+	return missing, xlx.SaveToDisk()
+}
+
+// monthTotals sums up the times of every month sheet
+func (xlx *XlFile) monthTotals() (map[string]int, error) {
+	totals := make(map[string]int)
+
+	for _, month := range xlx.MonthSheets() {
+		sheetName := MonthSheetName(month)
+
+		rows, err := xlx.file.GetRows(sheetName)
+		if err != nil {
+			return nil, sheetError(sheetName, err)
+		}
+
+		totals[sheetName] = 0
+
+		for rIx, row := range rows {
+			if rIx < 2 || len(row) < 5 || row[4] == "" {
+				continue
+			}
+
+			minutes, err := entryMinutes(row[4])
+			if err != nil {
+				continue
+			}
+
+			totals[sheetName] += minutes.Value()
+		}
+	}
+
+	return totals, nil
+}
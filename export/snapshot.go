@@ -0,0 +1,180 @@
+package export
+
+import (
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	log "github.com/sirupsen/logrus"
+	"mighty/merge"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const sheetSnapshotName = "Snapshot"
+
+func (xlx *XlFile) saveSnapshots(snapshots map[domain.TimeEntryId]merge.Snapshot) error {
+	log.Debug("Writing Snapshots...")
+
+	ids := make([]int, 0, len(snapshots))
+	for id := range snapshots {
+		ids = append(ids, int(id))
+	}
+
+	sort.Ints(ids)
+
+	xlx.file.DeleteSheet(sheetSnapshotName)
+	xlx.file.NewSheet(sheetSnapshotName)
+	row := 1
+
+	err := xlx.WriteHeader(sheetSnapshotName, row, []string{"entryId", "date", "minutes", "note", "projectId", "serviceId",
+		"updatedAt", "locked", "billable"})
+	if err != nil {
+		return err
+	}
+	row++
+
+	for _, id := range ids {
+		s := snapshots[domain.NewTimeEntryId(id)]
+
+		billable := ""
+		if s.Billable != nil {
+			billable = strconv.FormatBool(*s.Billable)
+		}
+
+		err = xlx.writeRow(sheetSnapshotName, row, []string{
+			s.Id.String(),
+			s.Date.String(),
+			strconv.Itoa(s.Minutes.Value()),
+			s.Note,
+			s.ProjectId.String(),
+			s.ServiceId.String(),
+			s.UpdatedAt.Format(time.RFC3339Nano),
+			strconv.FormatBool(s.Locked),
+			billable,
+		})
+		if err != nil {
+			return err
+		}
+		row++
+	}
+
+	return sheetError(sheetSnapshotName, xlx.file.SetSheetVisible(sheetSnapshotName, false))
+}
+
+// ReadSnapshots returns the state of the entries as they were at the last sync
+func (xlx *XlFile) ReadSnapshots() (map[domain.TimeEntryId]merge.Snapshot, error) {
+	log.Debug("Reading Snapshots...")
+
+	snapshots := make(map[domain.TimeEntryId]merge.Snapshot)
+
+	if xlx.file.GetSheetIndex(sheetSnapshotName) < 0 {
+		log.Warnf("%s has no snapshots yet, changes will be pushed without conflict detection", xlx.fileName)
+		return snapshots, nil
+	}
+
+	rows, err := xlx.file.GetRows(sheetSnapshotName)
+	if err != nil {
+		return nil, sheetError(sheetSnapshotName, err)
+	}
+
+	for rIx, row := range rows {
+		// skip header
+		if rIx == 0 || len(row) < 7 {
+			continue
+		}
+
+		rowNr := rIx + 1
+		cellErr := func(col rune, err error) error {
+			return cellError(sheetSnapshotName, fmt.Sprintf("%c%d", col, rowNr), err)
+		}
+
+		id, err := domain.ParseTimeEntryId(row[0])
+		if err != nil {
+			return nil, cellErr('A', err)
+		}
+
+		date, err := domain.ParseLocalDate(row[1])
+		if err != nil {
+			return nil, cellErr('B', err)
+		}
+
+		minutes, err := strconv.Atoi(row[2])
+		if err != nil {
+			return nil, cellErr('C', err)
+		}
+
+		projectId, err := domain.ParseProjectId(row[4])
+		if err != nil {
+			return nil, cellErr('E', err)
+		}
+
+		serviceId, err := domain.ParseServiceId(row[5])
+		if err != nil {
+			return nil, cellErr('F', err)
+		}
+
+		updatedAt, err := time.Parse(time.RFC3339Nano, row[6])
+		if err != nil {
+			return nil, cellErr('G', err)
+		}
+
+		// snapshots of older versions have no locked column
+		locked := false
+		if len(row) > 7 {
+			locked, err = strconv.ParseBool(row[7])
+			if err != nil {
+				return nil, cellErr('H', err)
+			}
+		}
+
+		// snapshots of older versions have no billable column, they don't know the synced billable flag
+		var billable *bool
+		if len(row) > 8 && row[8] != "" {
+			b, err := strconv.ParseBool(row[8])
+			if err != nil {
+				return nil, cellErr('I', err)
+			}
+			billable = &b
+		}
+
+		snapshots[id] = merge.Snapshot{
+			Id:        id,
+			Date:      date,
+			Minutes:   domain.NewMinutes(minutes),
+			Note:      row[3],
+			Billable:  billable,
+			ProjectId: projectId,
+			ServiceId: serviceId,
+			Locked:    locked,
+			UpdatedAt: updatedAt,
+		}
+	}
+
+	return snapshots, nil
+}
+
+// RemovedEntries returns the snapshots of the entries whose rows were removed from the month sheets since the last sync
+func (xlx *XlFile) RemovedEntries(snapshots map[domain.TimeEntryId]merge.Snapshot) ([]merge.Snapshot, error) {
+	rows, err := xlx.scanMonthSheets()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []merge.Snapshot
+
+	for id, s := range snapshots {
+		if _, ok := rows[id]; !ok {
+			removed = append(removed, s)
+		}
+	}
+
+	sort.Slice(removed, func(i, j int) bool {
+		if removed[i].Date.String() != removed[j].Date.String() {
+			return removed[i].Date.Before(removed[j].Date)
+		}
+
+		return removed[i].Id < removed[j].Id
+	})
+
+	return removed, nil
+}
@@ -0,0 +1,181 @@
+package export
+
+import (
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// Row is an entry of a month sheet along with its position in the workbook
+type Row struct {
+	Sheet  string
+	Number int
+	Entry  domain.TimeEntry
+}
+
+// Problem is an invalid cell of a month sheet
+type Problem struct {
+	Sheet   string
+	Row     int
+	Column  string
+	Message string
+}
+
+// Rules are the checks on top of parsing the rows
+type Rules struct {
+	RequireNote bool
+	// AllowDuplicates only warns about new rows which look like duplicates instead of reporting them as problems
+	AllowDuplicates bool
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("'%s'!%s%d: %s", p.Sheet, p.Column, p.Row, p.Message)
+}
+
+// ValidateRows checks the parsed rows against the rules, new rows with the same values as another row are likely
+// duplicates. Rows sharing an entry id are checked by ValidateIds.
+func ValidateRows(rows []Row, rules Rules) []Problem {
+	var problems []Problem
+
+	rowsByContent := make(map[string][]Row)
+
+	for _, row := range rows {
+		if rules.RequireNote && strings.TrimSpace(row.Entry.Note) == "" && row.Entry.Minutes.Value() > 0 {
+			problems = append(problems, Problem{row.Sheet, row.Number, "F", "missing entry description"})
+		}
+
+		if row.Entry.Minutes.Value() > 0 {
+			key := duplicateKey(&row.Entry)
+			rowsByContent[key] = append(rowsByContent[key], row)
+		}
+	}
+
+	for _, row := range rows {
+		if row.Entry.Id != 0 || row.Entry.Minutes.Value() == 0 {
+			continue
+		}
+
+		// a new row duplicates the rows of pushed entries anywhere and the new rows above it
+		below := false
+
+		for _, other := range rowsByContent[duplicateKey(&row.Entry)] {
+			if other.Sheet == row.Sheet && other.Number == row.Number {
+				below = true
+				continue
+			}
+
+			// the new rows below duplicate this one instead
+			if other.Entry.Id == 0 && below {
+				continue
+			}
+
+			problems = duplicate(problems, row, fmt.Sprintf("'%s'!%d", other.Sheet, other.Number), rules)
+			break
+		}
+	}
+
+	return problems
+}
+
+// ValidateIds reports the rows whose entry id is in another row as well. All month sheets are scanned, a row may be
+// copied to a month outside of the rows to push.
+func (xlx *XlFile) ValidateIds(rows []Row) ([]Problem, error) {
+	monthRows, err := xlx.scanMonthRows()
+	if err != nil {
+		return nil, err
+	}
+
+	rowsById := make(map[domain.TimeEntryId][]monthRow)
+	for _, r := range monthRows {
+		rowsById[r.id] = append(rowsById[r.id], r)
+	}
+
+	var problems []Problem
+
+	for _, row := range rows {
+		if row.Entry.Id == 0 {
+			continue
+		}
+
+		for _, other := range rowsById[row.Entry.Id] {
+			if other.sheet == row.Sheet && other.number == row.Number {
+				continue
+			}
+
+			problems = append(problems, Problem{row.Sheet, row.Number, "G",
+				fmt.Sprintf("entry %s is in '%s'!%d as well, copied rows need an empty id", row.Entry.Id, other.sheet, other.number)})
+			break
+		}
+	}
+
+	return problems, nil
+}
+
+// ValidateAgainstRemote checks the new rows against the entries which are in mite but not in the workbook, e.g.
+// created in mite since the last pull or by a push which failed before the pull
+func ValidateAgainstRemote(rows []Row, remote []*domain.TimeEntry, rules Rules) []Problem {
+	var problems []Problem
+
+	remoteByContent := make(map[string]*domain.TimeEntry, len(remote))
+	for _, entry := range remote {
+		remoteByContent[duplicateKey(entry)] = entry
+	}
+
+	for _, row := range rows {
+		if row.Entry.Id != 0 || row.Entry.Minutes.Value() == 0 {
+			continue
+		}
+
+		if entry, ok := remoteByContent[duplicateKey(&row.Entry)]; ok {
+			problems = duplicate(problems, row, fmt.Sprintf("mite entry %s, which isn't in the timesheet", entry.Id), rules)
+		}
+	}
+
+	return problems
+}
+
+func duplicate(problems []Problem, row Row, original string, rules Rules) []Problem {
+	p := Problem{row.Sheet, row.Number, "A", fmt.Sprintf("likely duplicate of %s (same date, project, service, time and note)", original)}
+
+	if rules.AllowDuplicates {
+		log.Warn(p)
+		return problems
+	}
+
+	return append(problems, p)
+}
+
+// duplicateKey returns the values which make two entries likely duplicates
+func duplicateKey(entry *domain.TimeEntry) string {
+	return fmt.Sprintf("%s|%d|%d|%d|%s", entry.Date, entry.Minutes.Value(), entry.ProjectId, entry.ServiceId,
+		strings.ToLower(strings.TrimSpace(entry.Note)))
+}
+
+// WithoutInvalid returns the rows which have no problem
+func WithoutInvalid(rows []Row, problems []Problem) []Row {
+	invalid := make(map[string]bool, len(problems))
+	for _, p := range problems {
+		invalid[fmt.Sprintf("%s!%d", p.Sheet, p.Row)] = true
+	}
+
+	var valid []Row
+
+	for _, row := range rows {
+		if !invalid[fmt.Sprintf("%s!%d", row.Sheet, row.Number)] {
+			valid = append(valid, row)
+		}
+	}
+
+	return valid
+}
+
+// Entries returns the entries of the rows
+func Entries(rows []Row) []domain.TimeEntry {
+	entries := make([]domain.TimeEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, row.Entry)
+	}
+
+	return entries
+}
@@ -1,22 +1,38 @@
 package export
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"github.com/elliotchance/orderedmap"
 	"github.com/leanovate/mite-go/domain"
 	log "github.com/sirupsen/logrus"
 	"github.com/xuri/excelize/v2"
+	"mighty/merge"
+	"mighty/reference"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
-	"unicode/utf8"
 )
 
 const (
-	sheetSummaryName = "Summary"
+	sheetSummaryName   = "Summary"
+	sheetServicesName  = "Services"
+	sheetProjectsName  = "Projects"
+	sheetCustomersName = "Customers"
+	monthSheetLayout   = "January 2006"
+)
+
+// actions of the optional Action column, which users can add right of the entries
+const (
+	actionHeader = "Action"
+	actionKeep   = "keep"
+	actionDelete = "delete"
+	actionIgnore = "ignore"
 )
 
 var (
+	monthHeader     = []string{"Date", "Project Name", "Service Name", "Billable?", "Time", "Entry Description"}
 	entryDateFormat = "yyyy-mm-dd;@"
 	entryTimeFormat = "hh:mm:ss;@"
 	entryAlignment  = &excelize.Alignment{
@@ -24,220 +40,416 @@ var (
 		Vertical:   "center",
 		WrapText:   true,
 	}
+	entryConflictFill = excelize.Fill{
+		Type:    "pattern",
+		Pattern: 1,
+		Color:   []string{"#F4CCCC"},
+	}
+	entryPendingFill = excelize.Fill{
+		Type:    "pattern",
+		Pattern: 1,
+		Color:   []string{"#FFF2CC"},
+	}
+	entryLockedFill = excelize.Fill{
+		Type:    "pattern",
+		Pattern: 1,
+		Color:   []string{"#D9D9D9"},
+	}
+	entryLockedFont = &excelize.Font{Color: "#808080"}
+	// cells stay editable when a sheet gets protected, only the rows of locked entries are read-only
+	entryUnlocked = &excelize.Protection{Locked: false}
+	entryLocked   = &excelize.Protection{Locked: true}
 )
 
 type XlFile struct {
 	fileName string
 	file     *excelize.File
+	// loaded is set once the workbook was read from disk
+	loaded bool
+	// activeSheet is the sheet the workbook was saved with, it is kept so the user returns to it
+	activeSheet string
 }
 
 func ExcelFile(fileName string) *XlFile {
 	return &XlFile{
 		fileName,
 		excelize.NewFile(),
+		false,
+		"",
 	}
 }
 
-func (xlx *XlFile) LoadAllEntries(entries []*domain.TimeEntry) {
+// FileName returns the path of the workbook
+func (xlx *XlFile) FileName() string {
+	return xlx.fileName
+}
+
+// LoadAllEntries updates the month sheets with the entries pulled from mite between from and to. Rows are updated
+// in place, entries new in mite are appended and rows of entries which are gone from mite are removed. Rows without
+// id, the cells right of the entry and any other sheet belong to the user and are kept. Conflicting rows keep their
+// local values and are flagged, so they can be resolved in the workbook. Entries locked in mite are greyed out and
+// their sheet gets protected, so they can't be edited by accident.
+func (xlx *XlFile) LoadAllEntries(from, to domain.LocalDate, entries []*domain.TimeEntry, conflicts []merge.Conflict) error {
 	log.Infof("Loading %d entries to %s", len(entries), xlx.fileName)
 
-	entryNotesStyle, err := xlx.file.NewStyle(&excelize.Style{Alignment: entryAlignment})
+	styles, err := xlx.newEntryStyles()
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
-	entryDateStyle, err := xlx.file.NewStyle(&excelize.Style{CustomNumFmt: &entryDateFormat, Alignment: entryAlignment})
-	entryTimeStyle, err := xlx.file.NewStyle(&excelize.Style{CustomNumFmt: &entryTimeFormat, Alignment: entryAlignment})
 
-	var monthEntriesCounts map[string]int = make(map[string]int)
-	monthEntriesTotalHours := orderedmap.NewOrderedMap()
+	previous := make(map[domain.TimeEntryId]merge.Snapshot)
+	if xlx.file.GetSheetIndex(sheetSnapshotName) >= 0 {
+		previous, err = xlx.ReadSnapshots()
+		if err != nil {
+			return err
+		}
+	}
 
+	conflictById := make(map[domain.TimeEntryId]merge.Conflict, len(conflicts))
+	for _, c := range conflicts {
+		conflictById[c.Local.Id] = c
+	}
+
+	remoteById := make(map[domain.TimeEntryId]*domain.TimeEntry, len(entries))
 	for _, entry := range entries {
+		remoteById[entry.Id] = entry
+	}
+
+	existing, err := xlx.scanMonthSheets()
+	if err != nil {
+		return err
+	}
+
+	// drop the flags of the last sync and the rows of entries which are gone or moved to another month
+	removals := make(map[string][]int)
+
+	for id, row := range existing {
+		err = xlx.clearPrompt(row.sheet, row.number)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := conflictById[id]; ok {
+			continue
+		}
+
+		if entry, ok := remoteById[id]; ok {
+			if MonthSheetName(entry.Date) != row.sheet {
+				removals[row.sheet] = append(removals[row.sheet], row.number)
+			}
+
+			continue
+		}
+
+		// rows outside of the pulled range are unknown to this pull
+		if row.dated && inRange(row.date, from, to) {
+			log.Debugf("[%s] entry is gone from mite, removing '%s'!%d", id, row.sheet, row.number)
+			removals[row.sheet] = append(removals[row.sheet], row.number)
+		}
+	}
+
+	err = xlx.removeRows(removals)
+	if err != nil {
+		return err
+	}
+
+	existing, err = xlx.scanMonthSheets()
+	if err != nil {
+		return err
+	}
+
+	created := make(map[string]bool)
+	touched := make(map[string]bool)
+	lockedMonths := make(map[string]bool)
+	pendingRows := make(map[string][]Row)
+	claimedRows := make(map[string]map[int]bool)
+
+	for _, entry := range entries {
+		if _, ok := conflictById[entry.Id]; ok {
+			// the local row is kept as it is
+			continue
+		}
 
 		log.Debugf("Loading entry %s", entry.Id)
 
-		entryMonth := fmt.Sprintf("%s %d", entry.Date.Month(), entry.Date.Year())
+		entryMonth := MonthSheetName(entry.Date)
+
+		if !touched[entryMonth] {
+			touched[entryMonth] = true
 
-		xlx.file.NewSheet(entryMonth)
-		currentRow := monthEntriesCounts[entryMonth]
-		currentRow++
+			if xlx.file.GetSheetIndex(entryMonth) < 0 {
+				created[entryMonth] = true
 
-		if currentRow == 1 {
-			xlx.WriteHeader(entryMonth, currentRow, []string{"Date", "Project Name", "Service Name", "Billable?", "Time", "Entry Description"})
-			currentRow += 2
+				err = xlx.newMonthSheet(entryMonth, styles)
+				if err != nil {
+					return err
+				}
+			} else {
+				rows, _, err := xlx.ReadAllEntriesBySheet(entryMonth)
+				if err != nil {
+					return err
+				}
+
+				for _, row := range rows {
+					if row.Entry.Id == 0 {
+						pendingRows[entryMonth] = append(pendingRows[entryMonth], row)
+					}
+				}
+
+				claimedRows[entryMonth] = make(map[int]bool)
+			}
+
+			err = xlx.WriteHeader(entryMonth, 1, monthHeader)
+			if err != nil {
+				return err
+			}
 		}
 
-		monthEntriesCounts[entryMonth] = currentRow
-		min := monthEntriesTotalHours.GetOrDefault(entryMonth, 0).(int) + entry.Minutes.Value()
-		monthEntriesTotalHours.Set(entryMonth, min)
+		if entry.Locked {
+			lockedMonths[entryMonth] = true
+		}
 
-		xlx.WriteEntry(entry.Id.String(), entryMonth, currentRow, []string{
-			entry.Date.String(),
-			fmt.Sprintf("%s", entry.ProjectName),
-			fmt.Sprintf("%s", entry.ServiceName),
-			strconv.FormatBool(entry.Billable),
-			entryTime(entry.Minutes),
-			entry.Note,
-		})
+		currentRow := 0
+		newRow := false
 
-		// fit cell row height
-		count := strings.Count(entry.Note, "\n")
-		if count > 1 {
-			rowHeight, err := xlx.file.GetRowHeight(entryMonth, currentRow)
+		if row, ok := existing[entry.Id]; ok {
+			currentRow = row.number
+		} else if row, ok := adoptRow(pendingRows[entryMonth], claimedRows[entryMonth], entry); ok {
+			log.Debugf("[%s] entry was created from '%s'!%d", entry.Id, entryMonth, row)
+			currentRow = row
 
+			// e.g. flagged as pending by an offline push
+			err = xlx.clearPrompt(entryMonth, row)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
+		} else {
+			newRow = true
 
-			rowHeight = rowHeight * float64(count)
-			err = xlx.file.SetRowHeight(entryMonth, currentRow, rowHeight)
+			currentRow, err = xlx.nextRow(entryMonth)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
 		}
+
+		err = xlx.writeEntryRow(entryMonth, currentRow, entry, styles, newRow)
+		if err != nil {
+			return err
+		}
 	}
 
-	// fit the cell width
-	log.Debug("Auto adjusting cell widths")
+	// flag the conflicts after the row styles, otherwise they'd be overwritten
+	for id, c := range conflictById {
+		row, ok := existing[id]
+		if !ok {
+			continue
+		}
 
-	for month := range monthEntriesCounts {
-		cols, err := xlx.file.GetCols(month)
+		err = xlx.file.SetCellStyle(row.sheet, fmt.Sprintf("A%d", row.number), fmt.Sprintf("F%d", row.number), styles.conflict)
+		if err != nil {
+			return cellError(row.sheet, fmt.Sprintf("A%d", row.number), err)
+		}
 
+		err = xlx.writeConflictPrompt(row.sheet, row.number, c)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
+	}
 
-		for colIx, col := range cols {
-			maxColWidth := 0
+	// only lift the protection mighty added itself, once the month has no locked entries anymore
+	for _, s := range previous {
+		month := MonthSheetName(s.Date)
+		if s.Locked && touched[month] && !lockedMonths[month] {
+			err = sheetError(month, xlx.file.UnprotectSheet(month))
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-			for _, rowCell := range col {
-				cellWidth := utf8.RuneCountInString(rowCell)
+	for month := range lockedMonths {
+		err = xlx.protectMonth(month, styles)
+		if err != nil {
+			return err
+		}
+	}
 
-				if cellWidth > maxColWidth {
-					maxColWidth = cellWidth
-				}
-			}
+	log.Debug("Auto adjusting cell widths")
 
-			colName, err := excelize.ColumnNumberToName(colIx + 1)
+	for month := range created {
+		err = xlx.fitColumns(month)
+		if err != nil {
+			return err
+		}
+	}
 
-			if err != nil {
-				log.Fatal(err)
-			}
+	log.Debug("Writing the summary...")
+	return xlx.writeSummary()
+}
 
-			if maxColWidth > excelize.MaxColumnWidth {
-				maxColWidth = 150
-			} else {
-				maxColWidth += 5
-			}
+// protectMonth protects the month sheet without a password, only the cells of locked entries become read-only.
+// The flags are written as is to the sheet, where true prohibits the action, so the zero value allows all of them.
+func (xlx *XlFile) protectMonth(sheetName string, styles *entryStyles) error {
+	err := xlx.unlockCells(sheetName, styles)
+	if err != nil {
+		return err
+	}
 
-			err = xlx.file.SetColWidth(month, colName, colName, float64(maxColWidth))
+	return sheetError(sheetName, xlx.file.ProtectSheet(sheetName, &excelize.FormatSheetProtection{}))
+}
+
+// unlockCells keeps every cell of the sheet but the ones of locked entries editable under protection, i.e. the
+// empty rows for new entries, the user's own columns and the rows the user styled. Excel locks every cell whose
+// style doesn't say otherwise, so cells without a style of their own get the unlocked style and the other styles
+// of the sheet are replaced by unlocked copies. The empty cells take the unlocked styles of their columns. The
+// default style is shared with the other sheets and stays as it is.
+func (xlx *XlFile) unlockCells(sheetName string, styles *entryStyles) error {
+	xfs := xlx.file.Styles.CellXfs
+	unlocked := xfs.Xf[styles.unlocked]
+
+	copies := map[int]int{0: styles.unlocked}
+
+	rows, err := xlx.file.GetRows(sheetName)
+	if err != nil {
+		return sheetError(sheetName, err)
+	}
 
+	for r, row := range rows {
+		for c := range row {
+			axis, err := excelize.CoordinatesToCellName(c+1, r+1)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
-			err = xlx.file.SetColStyle(month, colName, entryNotesStyle)
 
+			style, err := xlx.file.GetCellStyle(sheetName, axis)
 			if err != nil {
-				log.Fatal(err)
+				return sheetError(sheetName, err)
 			}
 
-			if colName == "A" {
-
-				err = xlx.file.SetColStyle(month, colName, entryDateStyle)
-
-				if err != nil {
-					log.Fatal(err)
-				}
+			if style == styles.locked {
+				continue
 			}
 
-			if colName == "E" {
-
-				err = xlx.file.SetColStyle(month, colName, entryTimeStyle)
+			xf := xfs.Xf[style]
+			if xf.Protection != nil && xf.Protection.Locked != nil && !*xf.Protection.Locked {
+				continue
+			}
 
-				if err != nil {
-					log.Fatal(err)
-				}
+			if _, ok := copies[style]; !ok {
+				xf.Protection = unlocked.Protection
+				xf.ApplyProtection = unlocked.ApplyProtection
+				xfs.Xf = append(xfs.Xf, xf)
+				xfs.Count = len(xfs.Xf)
+				copies[style] = len(xfs.Xf) - 1
 			}
 
+			err = xlx.file.SetCellStyle(sheetName, axis, axis, copies[style])
+			if err != nil {
+				return sheetError(sheetName, err)
+			}
 		}
-
 	}
-	log.Debug("Writing the summary...")
-	xlx.writeSummary(monthEntriesTotalHours)
+
+	return nil
 }
 
-func (xlx *XlFile) WriteHeader(sheetName string, row int, columnData []string) {
+func (xlx *XlFile) WriteHeader(sheetName string, row int, columnData []string) error {
 	startColumn := 'A'
 	for _, d := range columnData {
-		xlx.writeRichCellData(sheetName, fmt.Sprintf("%c%d", startColumn, row), []excelize.RichTextRun{
+		err := xlx.writeRichCellData(sheetName, fmt.Sprintf("%c%d", startColumn, row), []excelize.RichTextRun{
 			{
 				Text: d,
 				Font: &excelize.Font{
 					Bold: true,
 				},
 			}})
+		if err != nil {
+			return err
+		}
 		startColumn++
 	}
+	return nil
 }
 
-func (xlx *XlFile) WriteEntry(entryId, sheetName string, row int, columnData []string) {
-	startColumn := 'A'
-	for _, d := range columnData {
-		xlx.writeCellData(sheetName, fmt.Sprintf("%c%d", startColumn, row), d)
-		startColumn++
+func (xlx *XlFile) WriteEntry(entryId, sheetName string, row int, columnData []string) error {
+	err := xlx.writeRow(sheetName, row, columnData)
+	if err != nil {
+		return err
 	}
 	// hide this
-	entryIdAxis := fmt.Sprintf("%c%d", startColumn, row)
-	xlx.writeCellData(sheetName, entryIdAxis, entryId)
-	err := xlx.file.SetColVisible(sheetName, string(startColumn), false)
+	idColumn := string(rune('A' + len(columnData)))
+	err = xlx.writeCellData(sheetName, fmt.Sprintf("%s%d", idColumn, row), entryId)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	return sheetError(sheetName, xlx.file.SetColVisible(sheetName, idColumn, false))
 }
 
-func (xlx *XlFile) writeCellData(sheetName, axis string, cellData string) {
+// writeRow writes the cells of a row starting at column A
+func (xlx *XlFile) writeRow(sheetName string, row int, columnData []string) error {
+	startColumn := 'A'
+	for _, d := range columnData {
+		err := xlx.writeCellData(sheetName, fmt.Sprintf("%c%d", startColumn, row), d)
+		if err != nil {
+			return err
+		}
+		startColumn++
+	}
+	return nil
+}
+
+func (xlx *XlFile) writeCellData(sheetName, axis string, cellData string) error {
 
 	if xlx.file.GetSheetIndex(sheetName) < 0 {
 		xlx.file.NewSheet(sheetName)
 	}
 
-	err := xlx.file.SetCellValue(sheetName, axis, cellData)
-	if err != nil {
-		log.Fatal(err)
-	}
-
+	return cellError(sheetName, axis, xlx.file.SetCellValue(sheetName, axis, cellData))
 }
 
-func (xlx *XlFile) writeRichCellData(sheetName, axis string, cellData []excelize.RichTextRun) {
+func (xlx *XlFile) writeRichCellData(sheetName, axis string, cellData []excelize.RichTextRun) error {
 
 	if xlx.file.GetSheetIndex(sheetName) < 0 {
 		xlx.file.NewSheet(sheetName)
 	}
 
-	err := xlx.file.SetCellRichText(sheetName, axis, cellData)
+	return cellError(sheetName, axis, xlx.file.SetCellRichText(sheetName, axis, cellData))
+}
+
+// writeSummary lists the total time of every month sheet
+func (xlx *XlFile) writeSummary() error {
+	totals, err := xlx.monthTotals()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-}
 
-func (xlx *XlFile) writeSummary(totalHours *orderedmap.OrderedMap) {
-	xlx.WriteHeader(sheetSummaryName, 1, []string{"Month", "Total Hours"})
+	err = xlx.WriteHeader(sheetSummaryName, 1, []string{"Month", "Total Hours"})
+	if err != nil {
+		return err
+	}
 	row := 3
-	for _, month := range totalHours.Keys() {
+	for _, date := range xlx.MonthSheets() {
+		month := MonthSheetName(date)
 		axisMonth := fmt.Sprintf("A%d", row)
 		axisHours := fmt.Sprintf("B%d", row)
 
-		xlx.writeCellData(sheetSummaryName, axisMonth, month.(string))
-		totalMins := totalHours.GetOrDefault(month, 0).(int)
+		err = xlx.writeCellData(sheetSummaryName, axisMonth, month)
+		if err != nil {
+			return err
+		}
 
-		err := xlx.file.SetCellHyperLink(sheetSummaryName, axisMonth, fmt.Sprintf("'%s'!%s", month, "A1"), "Location")
+		err = xlx.file.SetCellHyperLink(sheetSummaryName, axisMonth, fmt.Sprintf("'%s'!%s", month, "A1"), "Location")
 		if err != nil {
-			log.Fatal(err)
+			return cellError(sheetSummaryName, axisMonth, err)
 		}
 
-		xlx.writeCellData(sheetSummaryName, axisHours, domain.NewMinutes(totalMins).String())
+		err = xlx.writeCellData(sheetSummaryName, axisHours, domain.NewMinutes(totals[month]).String())
+		if err != nil {
+			return err
+		}
 		row++
 	}
-
+	return xlx.removeRowsFrom(sheetSummaryName, row)
 }
 
 // ReloadFromDisk This is a destructive action. If you are currently working on sheet data
@@ -251,240 +463,685 @@ func (xlx *XlFile) ReloadFromDisk() error {
 	}
 
 	xlx.file = file
+	xlx.loaded = true
+
+	// a workbook without summary wasn't pulled completely yet, it opens with the summary once there is one
+	if file.GetSheetIndex(sheetSummaryName) >= 0 {
+		xlx.activeSheet = file.GetSheetName(file.GetActiveSheetIndex())
+	}
+
 	return nil
 }
 
 func (xlx *XlFile) SaveToDisk() error {
 	log.Debug("Writing to disk ...")
 
-	xlx.file.SetActiveSheet(xlx.file.GetSheetIndex(sheetSummaryName))
-	// delete the default sheet
-	xlx.file.DeleteSheet("Sheet1")
+	activeSheet := xlx.activeSheet
+	if xlx.file.GetSheetIndex(activeSheet) < 0 {
+		activeSheet = sheetSummaryName
+	}
+
+	xlx.file.SetActiveSheet(xlx.file.GetSheetIndex(activeSheet))
+	// delete the default sheet of a new workbook, unless the user put something in there
+	if rows, err := xlx.file.GetRows("Sheet1"); err == nil && len(rows) == 0 && xlx.file.SheetCount > 1 {
+		xlx.file.DeleteSheet("Sheet1")
+	}
 	return xlx.file.SaveAs(xlx.fileName)
 }
 
-func (xlx *XlFile) ReadAllEntriesBySheet(sheetName string) []domain.TimeEntry {
+// ReadAllEntriesBySheet parses the entry rows of a month sheet. Rows with unparsable cells or unknown
+// projects and services are reported as problems and left out of the returned rows. The error is
+// reserved for sheets which can't be read at all.
+func (xlx *XlFile) ReadAllEntriesBySheet(sheetName string) ([]Row, []Problem, error) {
 	log.Debugf("Reading all entries from %s sheet", sheetName)
 
-	pmap := xlx.readProjectId()
-	smap := xlx.readServiceId()
+	projects, err := xlx.readProjectId()
+	if err != nil {
+		return nil, nil, err
+	}
+	smap, err := xlx.readServiceId()
+	if err != nil {
+		return nil, nil, err
+	}
+	// the billable default and archived flag of services, and the archived flag of projects
+	billableMap, err := xlx.readFlags(sheetServicesName, 2)
+	if err != nil {
+		return nil, nil, err
+	}
+	archivedServices, err := xlx.readFlags(sheetServicesName, 3)
+	if err != nil {
+		return nil, nil, err
+	}
+	archivedProjects, err := xlx.readFlags(sheetProjectsName, 4)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	rows, err := xlx.file.GetRows(sheetName)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, sheetError(sheetName, err)
+	}
+	actionCol := -1
+	if len(rows) > 0 {
+		actionCol = findActionColumn(rows[0])
 	}
-	var timeEntries []domain.TimeEntry
+	var entryRows []Row
+	var problems []Problem
 	for rIx, row := range rows {
 		// skip header
 		if rIx > 1 {
+			if isBlank(row) {
+				continue
+			}
+
+			action := ""
+			if actionCol >= 0 && actionCol < len(row) {
+				action = strings.ToLower(strings.TrimSpace(row[actionCol]))
+			}
+
+			switch action {
+			case "", actionKeep:
+			case actionIgnore:
+				log.Debugf("'%s'!%d: ignoring the row", sheetName, rIx+1)
+				continue
+			case actionDelete:
+				deleteRow, ok, problem := deleteActionRow(sheetName, rIx+1, row)
+				if problem != nil {
+					problems = append(problems, *problem)
+				} else if ok {
+					entryRows = append(entryRows, deleteRow)
+				}
+				continue
+			default:
+				colName, _ := excelize.ColumnNumberToName(actionCol + 1)
+				problems = append(problems, Problem{sheetName, rIx + 1, colName,
+					fmt.Sprintf("unknown action %q, expected %s, %s or %s", row[actionCol], actionKeep, actionDelete, actionIgnore)})
+				continue
+			}
+
 			var entryDate domain.LocalDate
 			var entryTime domain.Minutes
 			var serviceId domain.ServiceId
 			var serviceName string
 			var projectId domain.ProjectId
 			var projectName string
+			var customerName string
 			var isEntryBillable bool
+			var billableCell string
 			var entryNotes string
 			var entryId domain.TimeEntryId
 
-			for cIx, cellData := range row {
-				cellNr := cIx + 1
-				colName, err := excelize.ColumnNumberToName(cellNr)
+			rowNr := rIx + 1
+			rowProblems := len(problems)
+			addProblem := func(colName, format string, a ...interface{}) {
+				problems = append(problems, Problem{sheetName, rowNr, colName, fmt.Sprintf(format, a...)})
+			}
+
+			// pad the row, so missing cells are validated as well
+			for len(row) < 7 {
+				row = append(row, "")
+			}
 
+			for cIx, cellData := range row {
+				colName, err := excelize.ColumnNumberToName(cIx + 1)
 				if err != nil {
-					log.Fatal(err)
+					return nil, nil, sheetError(sheetName, err)
 				}
 
 				switch colName {
 				case "A":
 					entryDate, err = domain.ParseLocalDate(cellData)
 					if err != nil {
-						log.Fatal(err)
+						addProblem(colName, "unable to parse date %q, expected yyyy-mm-dd", cellData)
 					}
 				case "B":
-
-					id, ok := pmap[strings.ToLower(cellData)]
-					if !ok {
-						log.Errorf("Unable to look id for project %s ", cellData)
+					project, err := projects.resolve(cellData)
+					if err != nil {
+						addProblem(colName, "%v", err)
 					}
-					projectId = id
-					projectName = cellData
+					projectId = project.Id
+					projectName = project.Name
+					customerName = project.CustomerName
 				case "C":
 
 					id, ok := smap[strings.ToLower(cellData)]
 
-					if !ok {
-						log.Errorf("Unable to look id for service %s ", cellData)
+					if !ok || id < 1 {
+						addProblem(colName, "unknown service %q", cellData)
 					}
 					serviceId = id
 					serviceName = cellData
 
 				case "D":
+					billableCell = cellData
+					if cellData == "" {
+						continue
+					}
+
 					isEntryBillable, err = strconv.ParseBool(cellData)
 					if err != nil {
-						log.Fatal(err)
+						addProblem(colName, "unable to parse billable %q, expected TRUE or FALSE", cellData)
 					}
 				case "E":
-					entryTime = entryMinutes(cellData)
+					entryTime, err = entryMinutes(cellData)
+					if err != nil {
+						addProblem(colName, "%v", err)
+					}
 				case "F":
 					entryNotes = cellData
 				case "G":
+					if cellData == "" {
+						continue
+					}
+
 					entryId, err = domain.ParseTimeEntryId(cellData)
 					if err != nil {
-						log.Fatal(err)
+						addProblem(colName, "unable to parse the hidden entry id %q", cellData)
 					}
 				}
 
 			}
 
-			timeEntries = append(timeEntries, domain.TimeEntry{
-				Id:          entryId,
-				Minutes:     entryTime,
-				Date:        entryDate,
-				Note:        entryNotes,
-				Billable:    isEntryBillable,
-				UserId:      domain.CurrentUser,
-				ProjectId:   projectId,
-				ServiceId:   serviceId,
-				ProjectName: projectName,
-				ServiceName: serviceName,
-				CreatedAt:   time.Now(),
-				UpdatedAt:   time.Now(),
+			// an empty billable cell takes the default of the service, like mite does for new entries
+			if billableCell == "" && serviceId > 0 {
+				billable, ok := billableMap[int(serviceId)]
+				if !ok {
+					addProblem("D", "billable is empty and the default of service %q is unknown, fill in TRUE or FALSE", serviceName)
+				}
+				isEntryBillable = billable
+			}
+
+			// archived items can't be booked anymore, historic entries keep them
+			if entryId == 0 && archivedProjects[int(projectId)] {
+				addProblem("B", "project %q is archived", reference.DisplayName(customerName, projectName))
+			}
+			if entryId == 0 && archivedServices[int(serviceId)] {
+				addProblem("C", "service %q is archived", serviceName)
+			}
+
+			if len(problems) > rowProblems {
+				continue
+			}
+
+			entryRows = append(entryRows, Row{
+				Sheet:  sheetName,
+				Number: rowNr,
+				Entry: domain.TimeEntry{
+					Id:           entryId,
+					Minutes:      entryTime,
+					Date:         entryDate,
+					Note:         entryNotes,
+					Billable:     isEntryBillable,
+					UserId:       domain.CurrentUser,
+					ProjectId:    projectId,
+					ServiceId:    serviceId,
+					ProjectName:  projectName,
+					CustomerName: customerName,
+					ServiceName:  serviceName,
+					CreatedAt:    time.Now(),
+					UpdatedAt:    time.Now(),
+				},
 			})
 		}
 
 	}
-	return timeEntries
+	return entryRows, problems, nil
+}
+
+// findActionColumn returns the index of the Action column in the header, or -1 if there is none
+func findActionColumn(header []string) int {
+	// the columns up to the hidden id belong to the entries
+	for cIx := 7; cIx < len(header); cIx++ {
+		if strings.EqualFold(strings.TrimSpace(header[cIx]), actionHeader) {
+			return cIx
+		}
+	}
+
+	return -1
+}
+
+// deleteActionRow returns the deletion of the entry of a row marked with the delete action. Only the id is needed,
+// so the other cells aren't validated. Rows without id were never pushed and have nothing to delete.
+func deleteActionRow(sheetName string, rowNr int, row []string) (Row, bool, *Problem) {
+	if len(row) < 7 || row[6] == "" {
+		log.Debugf("'%s'!%d: the row was never pushed, nothing to delete", sheetName, rowNr)
+		return Row{}, false, nil
+	}
+
+	entryId, err := domain.ParseTimeEntryId(row[6])
+	if err != nil {
+		return Row{}, false, &Problem{sheetName, rowNr, "G", fmt.Sprintf("unable to parse the hidden entry id %q", row[6])}
+	}
+
+	entryDate, err := domain.ParseLocalDate(row[0])
+	if err != nil {
+		// the row belongs to the month of its sheet anyway
+		entryDate, err = ParseMonthSheetName(sheetName)
+		if err != nil {
+			return Row{}, false, &Problem{sheetName, rowNr, "A", fmt.Sprintf("unable to parse date %q, expected yyyy-mm-dd", row[0])}
+		}
+	}
+
+	return Row{
+		Sheet:  sheetName,
+		Number: rowNr,
+		Entry: domain.TimeEntry{
+			Id:          entryId,
+			Date:        entryDate,
+			Minutes:     domain.NewMinutes(0),
+			UserId:      domain.CurrentUser,
+			ProjectName: row[1],
+			ServiceName: row[2],
+		},
+	}, true, nil
+}
+
+func isBlank(row []string) bool {
+	for _, cellData := range row {
+		if strings.TrimSpace(cellData) != "" {
+			return false
+		}
+	}
+
+	return true
 }
 
-func (xlx *XlFile) saveServiceId(serviceIdMap *orderedmap.OrderedMap) error {
+func (xlx *XlFile) saveServiceId(services []reference.Service) error {
 
 	log.Debug("Writing ServiceIds...")
 
-	sheetName := "Services"
+	sheetName := sheetServicesName
 	xlx.file.NewSheet(sheetName)
 	row := 1
-	xlx.WriteHeader(sheetName, row, []string{"Service Name", "serviceId"})
+	err := xlx.WriteHeader(sheetName, row, []string{"Service Name", "serviceId", "Billable?", "Archived?"})
+	if err != nil {
+		return err
+	}
 	row++
-	for _, name := range serviceIdMap.Keys() {
-		xlx.writeCellData(sheetName, fmt.Sprintf("A%d", row), name.(string))
-		xlx.writeCellData(sheetName, fmt.Sprintf("B%d", row), serviceIdMap.GetOrDefault(name, "").(domain.ServiceId).String())
+	for _, service := range services {
+		err = xlx.writeRow(sheetName, row, []string{service.Name, service.Id.String(), strconv.FormatBool(service.Billable),
+			strconv.FormatBool(service.Archived)})
+		if err != nil {
+			return err
+		}
+		err = xlx.hideArchived(sheetName, row, service.Archived)
+		if err != nil {
+			return err
+		}
 		row++
 	}
-	err := xlx.file.SetColVisible(sheetName, "B", false)
-	return err
+	err = xlx.removeRowsFrom(sheetName, row)
+	if err != nil {
+		return err
+	}
+	return sheetError(sheetName, xlx.file.SetColVisible(sheetName, "B", false))
 }
 
-func (xlx *XlFile) readServiceId() map[string]domain.ServiceId {
+func (xlx *XlFile) readServiceId() (map[string]domain.ServiceId, error) {
 	log.Debug("Reading ServiceIds...")
-	sheetName := "Services"
 	serviceIdMap := make(map[string]domain.ServiceId)
 
+	ids, err := xlx.readIds(sheetServicesName, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, id := range ids {
+		serviceIdMap[name] = domain.NewServiceId(id)
+	}
+	return serviceIdMap, nil
+}
+
+// hideArchived hides the row of an archived service or project, it stays in the sheet to resolve historic entries.
+// Rows of active ones are shown, in case they were archived before.
+func (xlx *XlFile) hideArchived(sheetName string, row int, archived bool) error {
+	return sheetError(sheetName, xlx.file.SetRowVisible(sheetName, row, !archived))
+}
+
+// readFlags reads a boolean column of a reference sheet by the hidden id in column B. Rows with an empty cell
+// are missing, e.g. in workbooks pulled before the column existed.
+func (xlx *XlFile) readFlags(sheetName string, column int) (map[int]bool, error) {
+	flagMap := make(map[int]bool)
+
 	rows, err := xlx.file.GetRows(sheetName)
 	if err != nil {
-		log.Fatal(err)
+		return nil, sheetError(sheetName, err)
 	}
 	for rIx, row := range rows {
 		// skip header
-		if rIx > 0 {
-			var serviceName string
-			var serviceId domain.ServiceId
-			for cIx, cellData := range row {
+		if rIx == 0 || len(row) <= column || row[column] == "" {
+			continue
+		}
 
-				cellNr := cIx + 1
-				colName, err := excelize.ColumnNumberToName(cellNr)
+		id, err := strconv.Atoi(row[1])
+		if err != nil {
+			return nil, cellError(sheetName, fmt.Sprintf("B%d", rIx+1), err)
+		}
 
-				if err != nil {
-					log.Fatal(err)
-				}
+		flag, err := strconv.ParseBool(row[column])
+		if err != nil {
+			colName, _ := excelize.ColumnNumberToName(column + 1)
+			return nil, cellError(sheetName, fmt.Sprintf("%s%d", colName, rIx+1), err)
+		}
 
-				switch colName {
-				case "A":
-					serviceName = cellData
-				case "B":
+		flagMap[id] = flag
+	}
+	return flagMap, nil
+}
 
-					id, err := strconv.Atoi(cellData)
-					if err != nil {
-						log.Fatal(err)
-					}
-					serviceId = domain.NewServiceId(id)
+func (xlx *XlFile) saveProjectId(projects []reference.Project) error {
+	log.Debug("Writing ProjectId...")
 
-				}
+	sheetName := sheetProjectsName
+	xlx.file.NewSheet(sheetName)
+	row := 1
+	err := xlx.WriteHeader(sheetName, row, []string{"Customer / Project", "projectId", "Customer", "Project Name", "Archived?"})
+	if err != nil {
+		return err
+	}
+	row++
+	for _, project := range projects {
+		err = xlx.writeRow(sheetName, row, []string{project.DisplayName(), project.Id.String(), project.CustomerName, project.Name,
+			strconv.FormatBool(project.Archived)})
+		if err != nil {
+			return err
+		}
+		err = xlx.hideArchived(sheetName, row, project.Archived)
+		if err != nil {
+			return err
+		}
+		row++
+	}
+	err = xlx.removeRowsFrom(sheetName, row)
+	if err != nil {
+		return err
+	}
+	return sheetError(sheetName, xlx.file.SetColVisible(sheetName, "B", false))
+}
 
-			}
-			serviceIdMap[strings.ToLower(serviceName)] = serviceId
-			log.Debugf("found %s=%s", serviceName, serviceId)
+// saveCustomers writes the customers of the projects
+func (xlx *XlFile) saveCustomers(projects []reference.Project) error {
+	log.Debug("Writing Customers...")
 
+	customerById := make(map[domain.CustomerId]reference.Customer)
+	for _, project := range projects {
+		if project.CustomerId > 0 {
+			customerById[project.CustomerId] = reference.Customer{Id: project.CustomerId, Name: project.CustomerName}
 		}
+	}
 
+	customers := make([]reference.Customer, 0, len(customerById))
+	for _, customer := range customerById {
+		customers = append(customers, customer)
 	}
-	return serviceIdMap
-}
 
-func (xlx *XlFile) saveProjectId(projectIdMap *orderedmap.OrderedMap) error {
-	log.Debug("Writing ProjectId...")
+	sort.Slice(customers, func(i, j int) bool {
+		return strings.ToLower(customers[i].Name) < strings.ToLower(customers[j].Name)
+	})
 
-	sheetName := "Projects"
+	sheetName := sheetCustomersName
 	xlx.file.NewSheet(sheetName)
 	row := 1
-	xlx.WriteHeader(sheetName, row, []string{"Project Name", "projectId"})
+	err := xlx.WriteHeader(sheetName, row, []string{"Customer Name", "customerId"})
+	if err != nil {
+		return err
+	}
 	row++
-	for _, name := range projectIdMap.Keys() {
-		xlx.writeCellData(sheetName, fmt.Sprintf("A%d", row), name.(string))
-		xlx.writeCellData(sheetName, fmt.Sprintf("B%d", row), projectIdMap.GetOrDefault(name, "").(domain.ProjectId).String())
+	for _, customer := range customers {
+		err = xlx.writeRow(sheetName, row, []string{customer.Name, customer.Id.String()})
+		if err != nil {
+			return err
+		}
 		row++
 	}
-	err := xlx.file.SetColVisible(sheetName, "B", false)
-	return err
+	err = xlx.removeRowsFrom(sheetName, row)
+	if err != nil {
+		return err
+	}
+	return sheetError(sheetName, xlx.file.SetColVisible(sheetName, "B", false))
+}
+
+// projectIds resolves the project cells of the month sheets, either by "Customer / Project" or by the project name
+// alone as long as only one customer has a project of that name
+type projectIds struct {
+	byDisplayName map[string]reference.Project
+	byName        map[string][]reference.Project
+}
+
+// resolve prefers the active projects, an archived one is only found if no active project has the name
+func (p *projectIds) resolve(cellData string) (reference.Project, error) {
+	name := strings.ToLower(strings.TrimSpace(cellData))
+
+	if project, ok := p.byDisplayName[name]; ok {
+		return project, nil
+	}
+
+	var candidates []reference.Project
+
+	for _, project := range p.byName[name] {
+		if !project.Archived {
+			candidates = append(candidates, project)
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = p.byName[name]
+	}
+
+	switch len(candidates) {
+	case 0:
+		return reference.Project{}, fmt.Errorf("unknown project %q", cellData)
+	case 1:
+		return candidates[0], nil
+	default:
+		names := make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			names = append(names, fmt.Sprintf("%q", c.DisplayName()))
+		}
+
+		return reference.Project{}, fmt.Errorf("project %q exists for several customers, use one of %s", cellData, strings.Join(names, ", "))
+	}
 }
-func (xlx *XlFile) readProjectId() map[string]domain.ProjectId {
+
+// readProjectId reads the Projects sheet, sheets pulled before customers were known only have the project name in column A
+func (xlx *XlFile) readProjectId() (*projectIds, error) {
 	log.Debug("Reading ProjectIds...")
-	sheetName := "Projects"
-	projectIdMap := make(map[string]domain.ProjectId)
+	projects := &projectIds{
+		byDisplayName: make(map[string]reference.Project),
+		byName:        make(map[string][]reference.Project),
+	}
 
-	rows, err := xlx.file.GetRows(sheetName)
+	rows, err := xlx.file.GetRows(sheetProjectsName)
 	if err != nil {
-		log.Fatal(err)
+		return nil, sheetError(sheetProjectsName, err)
 	}
 	for rIx, row := range rows {
 		// skip header
-		if rIx > 0 {
-			var projectName string
-			var projectId domain.ProjectId
+		if rIx == 0 || len(row) < 2 {
+			continue
+		}
 
-			for cIx, cellData := range row {
+		id, err := strconv.Atoi(row[1])
+		if err != nil {
+			return nil, cellError(sheetProjectsName, fmt.Sprintf("B%d", rIx+1), err)
+		}
 
-				cellNr := cIx + 1
-				colName, err := excelize.ColumnNumberToName(cellNr)
+		project := reference.Project{Id: domain.NewProjectId(id), Name: row[0]}
+		if len(row) > 3 {
+			project.CustomerName = row[2]
+			project.Name = row[3]
+		}
+		if len(row) > 4 && row[4] != "" {
+			project.Archived, err = strconv.ParseBool(row[4])
+			if err != nil {
+				return nil, cellError(sheetProjectsName, fmt.Sprintf("E%d", rIx+1), err)
+			}
+		}
 
-				if err != nil {
-					log.Fatal(err)
-				}
+		displayName := strings.ToLower(project.DisplayName())
+		if other, ok := projects.byDisplayName[displayName]; !ok || other.Archived {
+			projects.byDisplayName[displayName] = project
+		}
+		name := strings.ToLower(project.Name)
+		projects.byName[name] = append(projects.byName[name], project)
+		log.Debugf("found %s=%d", project.DisplayName(), id)
+	}
+	return projects, nil
+}
 
-				switch colName {
-				case "A":
-					projectName = cellData
-				case "B":
+// ResolveReference looks up a project and a service by name the same way the rows of the month sheets are resolved,
+// the service carries its billable default. Archived projects and services can't be booked and are an error.
+func (xlx *XlFile) ResolveReference(projectName, serviceName string) (reference.Project, reference.Service, error) {
+	projects, err := xlx.readProjectId()
+	if err != nil {
+		return reference.Project{}, reference.Service{}, err
+	}
+	smap, err := xlx.readServiceId()
+	if err != nil {
+		return reference.Project{}, reference.Service{}, err
+	}
+	billableMap, err := xlx.readFlags(sheetServicesName, 2)
+	if err != nil {
+		return reference.Project{}, reference.Service{}, err
+	}
+	archivedServices, err := xlx.readFlags(sheetServicesName, 3)
+	if err != nil {
+		return reference.Project{}, reference.Service{}, err
+	}
+	archivedProjects, err := xlx.readFlags(sheetProjectsName, 4)
+	if err != nil {
+		return reference.Project{}, reference.Service{}, err
+	}
 
-					id, err := strconv.Atoi(cellData)
-					if err != nil {
-						log.Fatal(err)
-					}
-					projectId = domain.NewProjectId(id)
+	project, err := projects.resolve(projectName)
+	if err != nil {
+		return reference.Project{}, reference.Service{}, err
+	}
+	project.Archived = archivedProjects[int(project.Id)]
+	if project.Archived {
+		return reference.Project{}, reference.Service{}, fmt.Errorf("project %q is archived", project.DisplayName())
+	}
 
-				}
+	id, ok := smap[strings.ToLower(strings.TrimSpace(serviceName))]
+	if !ok || id < 1 {
+		return reference.Project{}, reference.Service{}, fmt.Errorf("unknown service %q", serviceName)
+	}
+	service := reference.Service{Id: id, Name: strings.TrimSpace(serviceName), Archived: archivedServices[int(id)]}
+	if service.Archived {
+		return reference.Project{}, reference.Service{}, fmt.Errorf("service %q is archived", service.Name)
+	}
+	service.Billable, ok = billableMap[int(id)]
+	if !ok {
+		return reference.Project{}, reference.Service{}, fmt.Errorf("the billable default of service %q is unknown, pull the timesheet again", service.Name)
+	}
+
+	return project, service, nil
+}
 
+// readIds reads a reference sheet with the name in column A, the hidden id in column B and the archived flag in the
+// given column. The names are lower cased to look them up case-insensitively, an active item wins over an archived
+// one of the same name, which is only left for the rows of historic entries.
+func (xlx *XlFile) readIds(sheetName string, archivedColumn int) (map[string]int, error) {
+	idMap := make(map[string]int)
+	archivedNames := make(map[string]bool)
+
+	rows, err := xlx.file.GetRows(sheetName)
+	if err != nil {
+		return nil, sheetError(sheetName, err)
+	}
+	for rIx, row := range rows {
+		// skip header
+		if rIx == 0 || len(row) < 2 {
+			continue
+		}
+
+		id, err := strconv.Atoi(row[1])
+		if err != nil {
+			return nil, cellError(sheetName, fmt.Sprintf("B%d", rIx+1), err)
+		}
+
+		archived := false
+		if len(row) > archivedColumn && row[archivedColumn] != "" {
+			archived, err = strconv.ParseBool(row[archivedColumn])
+			if err != nil {
+				colName, _ := excelize.ColumnNumberToName(archivedColumn + 1)
+				return nil, cellError(sheetName, fmt.Sprintf("%s%d", colName, rIx+1), err)
 			}
-			projectIdMap[strings.ToLower(projectName)] = projectId
-			log.Debugf("found %s=%s", projectName, projectId)
 		}
 
+		name := strings.ToLower(row[0])
+		if _, ok := idMap[name]; ok && archived && !archivedNames[name] {
+			continue
+		}
+
+		idMap[name] = id
+		archivedNames[name] = archived
+		log.Debugf("found %s=%d", row[0], id)
 	}
-	return projectIdMap
+	return idMap, nil
 }
 
-func (xlx *XlFile) ReadAllEntries(date domain.LocalDate) []domain.TimeEntry {
-	return xlx.ReadAllEntriesBySheet(fmt.Sprintf("%s %d", date.Month(), date.Year()))
+func (xlx *XlFile) ReadAllEntries(date domain.LocalDate) ([]Row, []Problem, error) {
+	return xlx.ReadAllEntriesBySheet(MonthSheetName(date))
+}
+
+// MonthSheets returns the first day of every month which has a sheet in the workbook
+func (xlx *XlFile) MonthSheets() []domain.LocalDate {
+	var months []domain.LocalDate
+
+	for _, name := range xlx.file.GetSheetList() {
+		month, err := ParseMonthSheetName(name)
+		if err != nil {
+			continue
+		}
+
+		months = append(months, month)
+	}
+
+	sort.Slice(months, func(i, j int) bool {
+		return months[i].Before(months[j])
+	})
+
+	return months
+}
+
+// MonthFingerprints returns a hash of the content of every month sheet by its name, to tell which months changed
+// between two saves of the workbook
+func (xlx *XlFile) MonthFingerprints() (map[string]string, error) {
+	fingerprints := make(map[string]string)
+
+	for _, month := range xlx.MonthSheets() {
+		sheetName := MonthSheetName(month)
+
+		rows, err := xlx.file.GetRows(sheetName)
+		if err != nil {
+			return nil, sheetError(sheetName, err)
+		}
+
+		h := sha256.New()
+		for _, row := range rows {
+			_, _ = h.Write([]byte(strings.Join(row, "\x1f") + "\x1e"))
+		}
+
+		fingerprints[sheetName] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	return fingerprints, nil
+}
+
+// MonthSheetName returns the name of the sheet holding the entries of the given date, e.g. "September 2026"
+func MonthSheetName(date domain.LocalDate) string {
+	return fmt.Sprintf("%s %d", date.Month(), date.Year())
+}
+
+// ParseMonthSheetName returns the first day of the month named by a month sheet
+func ParseMonthSheetName(name string) (domain.LocalDate, error) {
+	t, err := time.ParseInLocation(monthSheetLayout, name, time.Local)
+	if err != nil {
+		return domain.LocalDate{}, err
+	}
+
+	return domain.NewLocalDate(t), nil
 }
 
 func (xlx *XlFile) GetSheets() {
@@ -493,27 +1150,145 @@ func (xlx *XlFile) GetSheets() {
 	}
 }
 
-func (xlx *XlFile) SaveAllEntries(entries []*domain.TimeEntry) error {
-	xlx.LoadAllEntries(entries)
+// SaveAllEntries updates the workbook with the entries pulled from mite between from and to, along with their
+// snapshots, which serve as base for the next sync. Conflicts keep their old snapshot so they stay conflicts until
+// they are resolved, entries outside of the range keep theirs as long as their rows are in the workbook.
+func (xlx *XlFile) SaveAllEntries(from, to domain.LocalDate, entries []*domain.TimeEntry, conflicts []merge.Conflict) error {
+	err := xlx.openOrCreate()
+	if err != nil {
+		return err
+	}
+
+	previous := make(map[domain.TimeEntryId]merge.Snapshot)
+	if xlx.file.GetSheetIndex(sheetSnapshotName) >= 0 {
+		previous, err = xlx.ReadSnapshots()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = xlx.LoadAllEntries(from, to, entries, conflicts)
+	if err != nil {
+		return err
+	}
+
+	rows, err := xlx.scanMonthSheets()
+	if err != nil {
+		return err
+	}
+
+	snapshots := make(map[domain.TimeEntryId]merge.Snapshot, len(rows))
+	for id := range rows {
+		if s, ok := previous[id]; ok {
+			snapshots[id] = s
+		}
+	}
+
+	for _, entry := range entries {
+		snapshots[entry.Id] = merge.NewSnapshot(entry)
+	}
+
+	for _, c := range conflicts {
+		if c.Base.Id != 0 {
+			snapshots[c.Local.Id] = c.Base
+		}
+	}
+
+	err = xlx.saveSnapshots(snapshots)
+	if err != nil {
+		return err
+	}
+
 	return xlx.SaveToDisk()
 }
 
-func (xlx *XlFile) SaveServiceProjects(sMap *orderedmap.OrderedMap, pMap *orderedmap.OrderedMap) {
-	err := xlx.saveServiceId(sMap)
+// AppendEntry appends an entry created outside of the timesheet to its month sheet along with its snapshot, so the
+// workbook is up-to-date without a pull
+func (xlx *XlFile) AppendEntry(entry *domain.TimeEntry) error {
+	err := xlx.openOrCreate()
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
 
+	styles, err := xlx.newEntryStyles()
+	if err != nil {
+		return err
 	}
-	err = xlx.saveProjectId(pMap)
+
+	month := MonthSheetName(entry.Date)
+	created := xlx.file.GetSheetIndex(month) < 0
+
+	if created {
+		err = xlx.newMonthSheet(month, styles)
+		if err != nil {
+			return err
+		}
+
+		err = xlx.WriteHeader(month, 1, monthHeader)
+		if err != nil {
+			return err
+		}
+	}
+
+	row, err := xlx.nextRow(month)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	err = xlx.writeEntryRow(month, row, entry, styles, true)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		err = xlx.fitColumns(month)
+		if err != nil {
+			return err
+		}
+	}
+
+	snapshots := make(map[domain.TimeEntryId]merge.Snapshot)
+	if xlx.file.GetSheetIndex(sheetSnapshotName) >= 0 {
+		snapshots, err = xlx.ReadSnapshots()
+		if err != nil {
+			return err
+		}
+	}
+
+	snapshots[entry.Id] = merge.NewSnapshot(entry)
+
+	err = xlx.saveSnapshots(snapshots)
+	if err != nil {
+		return err
+	}
+
+	err = xlx.writeSummary()
+	if err != nil {
+		return err
 	}
 
-	err = xlx.SaveToDisk()
+	return xlx.SaveToDisk()
+}
+
+func (xlx *XlFile) SaveServiceProjects(services []reference.Service, projects []reference.Project) error {
+	err := xlx.openOrCreate()
+	if err != nil {
+		return err
+	}
+	err = xlx.saveServiceId(services)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	err = xlx.saveProjectId(projects)
+	if err != nil {
+		return err
+	}
+	err = xlx.saveCustomers(projects)
+	if err != nil {
+		return err
 	}
 
+	return xlx.SaveToDisk()
 }
 
 func (xlx *XlFile) GenerateTemplate() {
@@ -521,41 +1296,44 @@ func (xlx *XlFile) GenerateTemplate() {
 }
 
 func entryTime(entryMins domain.Minutes) string {
-
 	if entryMins.Value() > 0 {
-		entryDuration, err := time.ParseDuration(entryMins.String())
-		if err != nil {
-			log.Fatal(err)
-		}
-		entryDuration = entryDuration.Round(time.Minute)
-		h := entryDuration / time.Hour
-		entryDuration -= h * time.Hour
-		m := entryDuration / time.Minute
-		return fmt.Sprintf("%02d:%02d:00", h, m)
+		return fmt.Sprintf("%02d:%02d:00", entryMins.Value()/60, entryMins.Value()%60)
 	}
-	return fmt.Sprint("00:00:00")
-
+	return "00:00:00"
 }
 
-func entryMinutes(entryTime string) domain.Minutes {
-	var dur time.Duration
-	var err error
-
-	if len(entryTime) == 8 {
-		dur, err = time.ParseDuration(entryTime[0:2] + "h" + entryTime[3:5] + "m" + entryTime[6:8] + "s")
+// entryMinutes parses the time cell of an entry, formatted as hh:mm:ss or hh:mm
+func entryMinutes(entryTime string) (domain.Minutes, error) {
+	parts := strings.Split(strings.TrimSpace(entryTime), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return domain.Minutes{}, fmt.Errorf("unable to parse time %q, expected hh:mm:ss", entryTime)
 	}
 
-	if len(entryTime) == 6 {
-		dur, err = time.ParseDuration(entryTime[0:2] + "h" + entryTime[3:5] + "m")
-	}
+	units := []string{"h", "m", "s"}
+	var duration strings.Builder
 
-	if err != nil {
-		log.Fatal(err)
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return domain.Minutes{}, fmt.Errorf("unable to parse time %q, expected hh:mm:ss", entryTime)
+		}
+
+		if value < 0 || strings.HasPrefix(part, "-") {
+			return domain.Minutes{}, fmt.Errorf("negative time %q", entryTime)
+		}
+
+		duration.WriteString(strconv.Itoa(value) + units[i])
 	}
-	minutes, err := domain.ParseMinutes(dur.String())
+
+	minutes, err := domain.ParseMinutes(duration.String())
 	if err != nil {
-		log.Fatal(err)
+		return domain.Minutes{}, err
 	}
-	log.Debugf("parsing %s to duration %s to minutes %s ", entryTime, dur, minutes)
-	return minutes
+	log.Debugf("parsing %s to duration %s to minutes %s ", entryTime, duration.String(), minutes)
+	return minutes, nil
+}
+
+// inRange compares by day, the dates may carry a time of day, e.g. domain.Today
+func inRange(date, from, to domain.LocalDate) bool {
+	return date.String() >= from.String() && date.String() <= to.String()
 }
@@ -4,10 +4,12 @@ go 1.17
 
 require (
 	github.com/elliotchance/orderedmap v1.4.0
+	github.com/fsnotify/fsnotify v1.5.1
 	github.com/leanovate/mite-go v0.4.0
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/cobra v1.2.1
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.9.0
 	github.com/xhit/go-str2duration/v2 v2.0.0
 	github.com/xuri/excelize/v2 v2.4.1
@@ -16,7 +18,6 @@ require (
 replace github.com/leanovate/mite-go v0.4.0 => github.com/foryouandyourcustomers/mite-go v0.4.1
 
 require (
-	github.com/fsnotify/fsnotify v1.5.1 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	github.com/magiconair/properties v1.8.5 // indirect
@@ -28,7 +29,6 @@ require (
 	github.com/spf13/afero v1.6.0 // indirect
 	github.com/spf13/cast v1.4.1 // indirect
 	github.com/spf13/jwalterweatherman v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/subosito/gotenv v1.2.0 // indirect
 	github.com/xuri/efp v0.0.0-20210322160811-ab561f5b45e3 // indirect
 	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5 // indirect
@@ -0,0 +1,213 @@
+package merge
+
+import (
+	"fmt"
+	"github.com/leanovate/mite-go/domain"
+	"time"
+)
+
+// Preference decides which side wins when an entry was changed both in the workbook and in mite
+type Preference string
+
+const (
+	PreferNone   Preference = ""
+	PreferLocal  Preference = "local"
+	PreferRemote Preference = "remote"
+)
+
+// Snapshot is the state of a time entry as it was in mite when it was last synced
+type Snapshot struct {
+	Id      domain.TimeEntryId
+	Date    domain.LocalDate
+	Minutes domain.Minutes
+	Note    string
+	// Billable is nil for snapshots written before billable was synced
+	Billable  *bool
+	ProjectId domain.ProjectId
+	ServiceId domain.ServiceId
+	Locked    bool
+	UpdatedAt time.Time
+}
+
+// Conflict is an entry which was changed in the workbook and in mite since the last sync
+type Conflict struct {
+	Local domain.TimeEntry
+	// Remote is nil when the entry was deleted in mite
+	Remote *domain.TimeEntry
+	// Base is empty when the workbook was pulled before snapshots existed
+	Base Snapshot
+}
+
+// Result holds the outcome of a three-way merge
+type Result struct {
+	// Push are the local changes which have to be sent to mite
+	Push []domain.TimeEntry
+	// Conflicts are left untouched in mite and flagged in the workbook
+	Conflicts []Conflict
+	// Locked are local changes of entries which are locked in mite, they can't be pushed
+	Locked []domain.TimeEntry
+}
+
+func ParsePreference(s string) (Preference, error) {
+	switch Preference(s) {
+	case PreferNone, PreferLocal, PreferRemote:
+		return Preference(s), nil
+	default:
+		return PreferNone, fmt.Errorf("unsupported preference %q, use either `local` or `remote`", s)
+	}
+}
+
+func NewSnapshot(entry *domain.TimeEntry) Snapshot {
+	billable := entry.Billable
+
+	return Snapshot{
+		Id:        entry.Id,
+		Date:      entry.Date,
+		Minutes:   entry.Minutes,
+		Note:      entry.Note,
+		Billable:  &billable,
+		ProjectId: entry.ProjectId,
+		ServiceId: entry.ServiceId,
+		Locked:    entry.Locked,
+		UpdatedAt: entry.UpdatedAt,
+	}
+}
+
+// Entry returns the entry as it was in mite at the last sync, e.g. to merge against while mite is unreachable
+func (s Snapshot) Entry() *domain.TimeEntry {
+	entry := &domain.TimeEntry{
+		Id:        s.Id,
+		Date:      s.Date,
+		Minutes:   s.Minutes,
+		Note:      s.Note,
+		ProjectId: s.ProjectId,
+		ServiceId: s.ServiceId,
+		Locked:    s.Locked,
+		UpdatedAt: s.UpdatedAt,
+	}
+
+	if s.Billable != nil {
+		entry.Billable = *s.Billable
+	}
+
+	return entry
+}
+
+// Matches reports whether the entry carries the same synced values as the snapshot
+func (s Snapshot) Matches(entry *domain.TimeEntry) bool {
+	billable := entry.Billable
+	if s.Billable != nil {
+		billable = *s.Billable
+	}
+
+	return sameContent(&domain.TimeEntry{
+		Date:      s.Date,
+		Minutes:   s.Minutes,
+		Note:      s.Note,
+		Billable:  billable,
+		ProjectId: s.ProjectId,
+		ServiceId: s.ServiceId,
+	}, entry)
+}
+
+func (c Conflict) String() string {
+	if c.Remote == nil {
+		return fmt.Sprintf("[%s] %s %s was changed locally but deleted in mite", c.Local.Id, c.Local.Date, c.Local.ProjectName)
+	}
+
+	return fmt.Sprintf("[%s] %s %s was changed locally (%s, %q) and in mite (%s, %q)", c.Local.Id, c.Local.Date, c.Local.ProjectName,
+		c.Local.Minutes.String(), c.Local.Note, c.Remote.Minutes.String(), c.Remote.Note)
+}
+
+// ThreeWay merges the local entries of the workbook with the remote entries of mite using the snapshots
+// of the last sync as common base. Local-only changes are pushed, remote-only changes are left for the pull
+// and entries changed on both sides are resolved according to prefer or reported as conflicts.
+func ThreeWay(local []domain.TimeEntry, base map[domain.TimeEntryId]Snapshot, remote []*domain.TimeEntry, prefer Preference) *Result {
+	remoteById := make(map[domain.TimeEntryId]*domain.TimeEntry, len(remote))
+	for _, r := range remote {
+		remoteById[r.Id] = r
+	}
+
+	result := &Result{}
+
+	for _, entry := range local {
+		if entry.Id == 0 {
+			result.Push = append(result.Push, entry)
+			continue
+		}
+
+		r := remoteById[entry.Id]
+		snapshot, known := base[entry.Id]
+
+		if !known {
+			// workbook was pulled before snapshots existed, fall back to pushing whatever differs
+			switch {
+			case r == nil:
+				// deleted in mite, without a base it's unknown whether it was changed in the workbook
+				result.Conflicts = append(result.Conflicts, Conflict{Local: entry})
+			case sameContent(r, &entry):
+			case r.Locked:
+				result.Locked = append(result.Locked, entry)
+			default:
+				result.Push = append(result.Push, entry)
+			}
+
+			continue
+		}
+
+		if snapshot.Matches(&entry) {
+			// unchanged locally, remote changes will be pulled
+			continue
+		}
+
+		if r != nil && r.Locked {
+			// locked entries are read-only, the pull restores their values
+			result.Locked = append(result.Locked, entry)
+			continue
+		}
+
+		if r != nil && r.UpdatedAt.Equal(snapshot.UpdatedAt) {
+			result.Push = append(result.Push, entry)
+			continue
+		}
+
+		if r != nil && sameContent(r, &entry) {
+			// both sides made the same change
+			continue
+		}
+
+		if r == nil && entry.Minutes.Value() == 0 {
+			// deleted on both sides
+			continue
+		}
+
+		switch prefer {
+		case PreferLocal:
+			if r == nil {
+				// entry is gone in mite, re-create it
+				entry.Id = 0
+			}
+
+			result.Push = append(result.Push, entry)
+		case PreferRemote:
+			continue
+		default:
+			result.Conflicts = append(result.Conflicts, Conflict{
+				Local:  entry,
+				Remote: r,
+				Base:   snapshot,
+			})
+		}
+	}
+
+	return result
+}
+
+func sameContent(a, b *domain.TimeEntry) bool {
+	return a.Date.String() == b.Date.String() &&
+		a.Minutes.Value() == b.Minutes.Value() &&
+		a.Note == b.Note &&
+		a.Billable == b.Billable &&
+		a.ProjectId == b.ProjectId &&
+		a.ServiceId == b.ServiceId
+}
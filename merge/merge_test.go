@@ -0,0 +1,146 @@
+package merge
+
+import (
+	"github.com/leanovate/mite-go/domain"
+	"testing"
+	"time"
+)
+
+var lastSync = time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+
+// synced returns the entry as it was pulled at the last sync
+func synced() domain.TimeEntry {
+	date, _ := domain.ParseLocalDate("2021-03-04")
+
+	return domain.TimeEntry{
+		Id:        domain.NewTimeEntryId(1),
+		Date:      date,
+		Minutes:   domain.NewMinutes(60),
+		Note:      "review",
+		Billable:  true,
+		ProjectId: domain.NewProjectId(2),
+		ServiceId: domain.NewServiceId(3),
+		UpdatedAt: lastSync,
+	}
+}
+
+func withNote(entry domain.TimeEntry, note string) domain.TimeEntry {
+	entry.Note = note
+	return entry
+}
+
+// changedInMite returns the entry with the note changed in mite after the last sync
+func changedInMite(note string) *domain.TimeEntry {
+	entry := withNote(synced(), note)
+	entry.UpdatedAt = lastSync.Add(time.Hour)
+
+	return &entry
+}
+
+func lockedInMite() *domain.TimeEntry {
+	entry := synced()
+	entry.Locked = true
+	entry.UpdatedAt = lastSync.Add(time.Hour)
+
+	return &entry
+}
+
+func TestThreeWay(t *testing.T) {
+	unchanged := synced()
+	base := map[domain.TimeEntryId]Snapshot{unchanged.Id: NewSnapshot(&unchanged)}
+
+	deleted := synced()
+	deleted.Minutes = domain.NewMinutes(0)
+
+	created := withNote(synced(), "new")
+	created.Id = 0
+
+	tests := []struct {
+		name   string
+		local  domain.TimeEntry
+		base   map[domain.TimeEntryId]Snapshot
+		remote *domain.TimeEntry
+		prefer Preference
+		// expected outcome, either push, conflict, locked or none
+		expected string
+		// pushedId is the id of the pushed entry, 0 creates it
+		pushedId domain.TimeEntryId
+	}{
+		{"new row", created, base, nil, PreferNone, "push", 0},
+		{"unchanged", unchanged, base, &unchanged, PreferNone, "none", 0},
+		{"changed locally", withNote(unchanged, "local"), base, &unchanged, PreferNone, "push", 1},
+		{"changed in mite", unchanged, base, changedInMite("remote"), PreferNone, "none", 0},
+		{"changed on both sides", withNote(unchanged, "local"), base, changedInMite("remote"), PreferNone, "conflict", 0},
+		{"same change on both sides", withNote(unchanged, "same"), base, changedInMite("same"), PreferNone, "none", 0},
+		{"changed on both sides, prefer local", withNote(unchanged, "local"), base, changedInMite("remote"), PreferLocal, "push", 1},
+		{"changed on both sides, prefer remote", withNote(unchanged, "local"), base, changedInMite("remote"), PreferRemote, "none", 0},
+		{"deleted locally", deleted, base, &unchanged, PreferNone, "push", 1},
+		{"deleted locally and in mite", deleted, base, nil, PreferNone, "none", 0},
+		{"deleted in mite", unchanged, base, nil, PreferNone, "none", 0},
+		{"changed locally, deleted in mite", withNote(unchanged, "local"), base, nil, PreferNone, "conflict", 0},
+		{"changed locally, deleted in mite, prefer local", withNote(unchanged, "local"), base, nil, PreferLocal, "push", 0},
+		{"changed locally, deleted in mite, prefer remote", withNote(unchanged, "local"), base, nil, PreferRemote, "none", 0},
+		{"deleted in mite without snapshot", unchanged, nil, nil, PreferNone, "conflict", 0},
+		{"unchanged without snapshot", unchanged, nil, &unchanged, PreferNone, "none", 0},
+		{"changed without snapshot", withNote(unchanged, "local"), nil, &unchanged, PreferNone, "push", 1},
+		{"changed locally, locked in mite", withNote(unchanged, "local"), base, lockedInMite(), PreferNone, "locked", 0},
+		{"changed locally, locked in mite, prefer local", withNote(unchanged, "local"), base, lockedInMite(), PreferLocal, "locked", 0},
+		{"unchanged, locked in mite", unchanged, base, lockedInMite(), PreferNone, "none", 0},
+		{"changed without snapshot, locked in mite", withNote(unchanged, "local"), nil, lockedInMite(), PreferNone, "locked", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var remote []*domain.TimeEntry
+			if test.remote != nil {
+				remote = append(remote, test.remote)
+			}
+
+			result := ThreeWay([]domain.TimeEntry{test.local}, test.base, remote, test.prefer)
+
+			outcome := "none"
+			switch {
+			case len(result.Push) == 1 && len(result.Conflicts) == 0 && len(result.Locked) == 0:
+				outcome = "push"
+			case len(result.Push) == 0 && len(result.Conflicts) == 1 && len(result.Locked) == 0:
+				outcome = "conflict"
+			case len(result.Push) == 0 && len(result.Conflicts) == 0 && len(result.Locked) == 1:
+				outcome = "locked"
+			case len(result.Push) != 0 || len(result.Conflicts) != 0 || len(result.Locked) != 0:
+				t.Fatalf("expected a single outcome, got %d pushes, %d conflicts and %d locked", len(result.Push),
+					len(result.Conflicts), len(result.Locked))
+			}
+
+			if outcome != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, outcome)
+			}
+
+			if outcome == "push" && result.Push[0].Id != test.pushedId {
+				t.Errorf("expected the push of id %s, got %s", test.pushedId, result.Push[0].Id)
+			}
+
+			if outcome == "conflict" {
+				c := result.Conflicts[0]
+				if (c.Remote == nil) != (test.remote == nil) {
+					t.Errorf("expected the remote entry %v in the conflict, got %v", test.remote, c.Remote)
+				}
+
+				if test.base == nil && c.Base.Id != 0 {
+					t.Errorf("expected an empty base without snapshot, got %s", c.Base.Id)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePreference(t *testing.T) {
+	for _, s := range []string{"", "local", "remote"} {
+		if _, err := ParsePreference(s); err != nil {
+			t.Errorf("unable to parse %q: %v", s, err)
+		}
+	}
+
+	if _, err := ParsePreference("both"); err == nil {
+		t.Error("expected an error for an unsupported preference")
+	}
+}
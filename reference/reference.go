@@ -0,0 +1,48 @@
+package reference
+
+import (
+	"github.com/leanovate/mite-go/domain"
+)
+
+// customerSeparator separates the customer from the project in display names, e.g. "ACME / Maintenance"
+const customerSeparator = " / "
+
+// Service is a mite service as listed in the Services sheet
+type Service struct {
+	Id   domain.ServiceId
+	Name string
+	// Billable is the default of new entries booked on the service
+	Billable bool
+	// Archived services keep their historic entries, but can't be booked anymore
+	Archived bool
+}
+
+// Project is a mite project as listed in the Projects sheet
+type Project struct {
+	Id           domain.ProjectId
+	Name         string
+	CustomerId   domain.CustomerId
+	CustomerName string
+	// Archived projects keep their historic entries, but can't be booked anymore
+	Archived bool
+}
+
+// Customer is a mite customer as listed in the Customers sheet
+type Customer struct {
+	Id   domain.CustomerId
+	Name string
+}
+
+// DisplayName returns the name of the project as shown in the timesheet
+func (p Project) DisplayName() string {
+	return DisplayName(p.CustomerName, p.Name)
+}
+
+// DisplayName returns the name of a project qualified by its customer, projects without customer keep their name
+func DisplayName(customerName, projectName string) string {
+	if customerName == "" {
+		return projectName
+	}
+
+	return customerName + customerSeparator + projectName
+}